@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"gorm.io/gorm"
+
+	"github.com/oggyb/muzz-exercise/internal/config"
+	"github.com/oggyb/muzz-exercise/internal/db"
+	"github.com/oggyb/muzz-exercise/internal/repository"
+)
+
+// backfill recomputes the user_stats counters of every user from the
+// decisions table. Run it once after the user_stats migration, or any time
+// the counters are suspected to have drifted. It is safe while the server
+// is running: each user is recomputed under their stats row lock.
+func main() {
+	batch := flag.Int("batch", 500, "users loaded per batch")
+
+	// Load configuration
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:], config.ProfileTool)
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+
+	database, err := db.NewDB(cfg)
+	if err != nil {
+		log.Fatalf("failed to init db: %v", err)
+	}
+
+	shards := []*gorm.DB{database}
+	if len(cfg.DB.ShardDSNs) > 0 {
+		if shards, err = db.NewShards(cfg); err != nil {
+			log.Fatalf("failed to init db shards: %v", err)
+		}
+	}
+	repo := repository.NewShardedDecisionRepository(repository.NewShardRouter(shards...))
+
+	ctx := context.Background()
+	var users []db.User
+	done := 0
+	result := database.WithContext(ctx).Select("id").FindInBatches(&users, *batch, func(tx *gorm.DB, _ int) error {
+		for _, u := range users {
+			if _, err := repo.RecomputeUserStats(ctx, u.ID); err != nil {
+				return err
+			}
+		}
+		done += len(users)
+		log.Printf("backfilled %d users", done)
+		return nil
+	})
+	if result.Error != nil {
+		log.Fatalf("backfill failed after %d users: %v", done, result.Error)
+	}
+
+	log.Println("Backfill completed.")
+}
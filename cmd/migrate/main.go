@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/oggyb/muzz-exercise/internal/config"
+	"github.com/oggyb/muzz-exercise/internal/db"
+	"github.com/oggyb/muzz-exercise/internal/db/migrate"
+)
+
+const usage = `usage: migrate <command> [flags]
+
+up, down and status run against the main database and then every
+shard listed in DB_SHARD_DSNS. They also accept the server's config
+flags, e.g. --config config.yaml.
+
+commands:
+  up                 apply all pending migrations
+  down [-steps N]    revert the last N applied migrations (default 1)
+  status             list migrations and whether they are applied
+  create <name>      scaffold up/down files for every dialect
+                     [-dir internal/db/migrations]
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, args := os.Args[1], os.Args[2:]
+
+	if cmd == "create" {
+		fs := flag.NewFlagSet("create", flag.ExitOnError)
+		dir := fs.String("dir", "internal/db/migrations", "migrations directory")
+		_ = fs.Parse(args)
+		if fs.NArg() == 0 {
+			log.Fatal("create: migration name is required")
+		}
+		files, err := migrate.Create(*dir, strings.Join(fs.Args(), "_"))
+		if err != nil {
+			log.Fatalf("create: %v", err)
+		}
+		for _, f := range files {
+			fmt.Println(f)
+		}
+		return
+	}
+
+	// Load configuration; config flags (e.g. --config) follow the command
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	steps := fs.Int("steps", 1, "number of migrations to revert (down)")
+	cfg, err := config.Load(fs, args, config.ProfileTool)
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
+
+	targets, err := loadTargets(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, t := range targets {
+		switch cmd {
+		case "up":
+			applied, err := t.m.Up(ctx)
+			for _, mig := range applied {
+				log.Printf("[%s] applied %04d_%s", t.name, mig.Version, mig.Name)
+			}
+			if err != nil {
+				log.Fatalf("[%s] up: %v", t.name, err)
+			}
+			log.Printf("[%s] Schema is up to date (%d applied).", t.name, len(applied))
+
+		case "down":
+			reverted, err := t.m.Down(ctx, *steps)
+			for _, mig := range reverted {
+				log.Printf("[%s] reverted %04d_%s", t.name, mig.Version, mig.Name)
+			}
+			if err != nil {
+				log.Fatalf("[%s] down: %v", t.name, err)
+			}
+
+		case "status":
+			statuses, err := t.m.Status(ctx)
+			if err != nil {
+				log.Fatalf("[%s] status: %v", t.name, err)
+			}
+			fmt.Printf("[%s]\n", t.name)
+			for _, s := range statuses {
+				state := "pending"
+				if s.Applied {
+					state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+				}
+				fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, state)
+			}
+
+		default:
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+	}
+}
+
+type target struct {
+	name string
+	m    *migrate.Migrator
+}
+
+// loadTargets returns the main database followed by every decision shard.
+func loadTargets(cfg *config.Config) ([]target, error) {
+	database, err := db.NewDB(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init db: %w", err)
+	}
+	m, err := migrate.New(database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	targets := []target{{name: "main", m: m}}
+
+	shards, err := db.NewShards(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init db shards: %w", err)
+	}
+	for i, shard := range shards {
+		m, err := migrate.NewShard(shard)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load shard migrations: %w", err)
+		}
+		targets = append(targets, target{name: fmt.Sprintf("shard-%d", i), m: m})
+	}
+	return targets, nil
+}
@@ -1,21 +1,36 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"github.com/oggyb/muzz-exercise/internal/config"
 	"log"
+	"os"
 
 	"github.com/oggyb/muzz-exercise/internal/db"
+	"github.com/oggyb/muzz-exercise/internal/db/migrate"
 )
 
 func main() {
 	// Load configuration
-	cfg := config.New()
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:], config.ProfileTool)
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
+	}
 
 	database, err := db.NewDB(cfg)
 	if err != nil {
 		log.Fatalf("failed to init db: %v", err)
 	}
 
+	migrator, err := migrate.New(database)
+	if err != nil {
+		log.Fatalf("failed to load migrations: %v", err)
+	}
+	if err := migrator.EnsureCurrent(context.Background()); err != nil {
+		log.Fatalf("database schema check failed: %v", err)
+	}
+
 	if err := db.SeedTestData(database); err != nil {
 		log.Fatalf("failed to seed: %v", err)
 	}
@@ -2,53 +2,260 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"github.com/oggyb/muzz-exercise/internal/app"
+	"github.com/oggyb/muzz-exercise/internal/auth"
 	"github.com/oggyb/muzz-exercise/internal/cache"
 	"github.com/oggyb/muzz-exercise/internal/config"
 	"github.com/oggyb/muzz-exercise/internal/db"
+	"github.com/oggyb/muzz-exercise/internal/db/migrate"
 	"github.com/oggyb/muzz-exercise/internal/logger"
+	"github.com/oggyb/muzz-exercise/internal/metrics"
+	"github.com/oggyb/muzz-exercise/internal/repository"
 	"github.com/oggyb/muzz-exercise/internal/server"
+	authsvc "github.com/oggyb/muzz-exercise/internal/service/auth"
 	"github.com/oggyb/muzz-exercise/internal/service/explore"
+	"github.com/oggyb/muzz-exercise/internal/tracing"
+	"github.com/oggyb/muzz-exercise/internal/utils/pagination"
+	"gorm.io/gorm"
+	"net/http"
+	"os"
+	"time"
 )
 
+// redisStartupWait is how long startup waits for Redis before continuing
+// in degraded mode.
+const redisStartupWait = 5 * time.Second
+
 func main() {
-	cfg := config.New()
+	// Config: defaults < YAML file (--config) < env < flags. Invalid settings
+	// are all reported at once and stop startup.
+	printConfig := flag.Bool("print-config", false, "print the configuration, secrets redacted, and exit")
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:], config.ProfileServer)
+	if *printConfig && cfg != nil {
+		if err := cfg.Redacted().WriteYAML(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "print config: %v\n", err)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	if *printConfig {
+		return
+	}
 
 	// Init logger (global singleton)
 	logger.InitFromConfig(cfg)
 	log := logger.L() // slog.Logger pointer
 
-	// Init DB
-	database, err := db.NewDB(cfg)
+	// Lifecycle: SIGINT/SIGTERM cancel startup and drain the server; every
+	// component registered below is stopped in reverse order. The deferred
+	// Shutdown also releases what was opened when startup fails half-way.
+	lc := app.NewLifecycle(log, cfg.App.ShutdownTimeout)
+	defer lc.Shutdown()
+	ctx := lc.Context()
+
+	// SIGHUP re-reads LOG_LEVEL_FILE (or restores LOG_LEVEL)
+	lc.Go("log level reload", func(ctx context.Context) error {
+		logger.WatchLevel(ctx, cfg.Log.LevelFile, cfg.Log.Level)
+		return nil
+	})
+
+	// Tracing: registered first so it is stopped last and flushes the
+	// spans of the drain.
+	shutdownTracing, err := tracing.Init(ctx, cfg)
+	if err != nil {
+		log.Error("failed to init tracing", "err", err)
+		return
+	}
+	lc.OnStop("tracing", shutdownTracing)
+
+	// Init DB, waiting for it to come up (e.g. under docker compose)
+	startCtx, cancelStart := context.WithTimeout(ctx, cfg.App.StartupTimeout)
+	defer cancelStart()
+	var database *gorm.DB
+	err = app.Retry(startCtx, log, "database", func() (err error) {
+		database, err = db.NewDB(cfg)
+		return err
+	})
 	if err != nil {
 		log.Error("failed to init db", "err", err)
 		return
 	}
+	lc.OnStop("database", func(context.Context) error { return db.Close(database) })
 
-	// Init Redis
-	redisCache := cache.NewRedisCache(cfg)
-	if err := redisCache.Ping(context.Background()); err != nil {
-		log.Error("failed to connect to redis", "err", err)
+	// Refuse to start against an outdated schema; run `migrate up` first.
+	migrator, err := migrate.New(database)
+	if err != nil {
+		log.Error("failed to load migrations", "err", err)
 		return
 	}
+	if err := migrator.EnsureCurrent(ctx); err != nil {
+		log.Error("database schema check failed", "err", err)
+		return
+	}
+
+	// Decision shards (optional): without DB_SHARD_DSNS decisions stay on the main DB
+	shards := []*gorm.DB{database}
+	if len(cfg.DB.ShardDSNs) > 0 {
+		err := app.Retry(startCtx, log, "db shards", func() (err error) {
+			shards, err = db.NewShards(cfg)
+			return err
+		})
+		if err != nil {
+			log.Error("failed to init db shards", "err", err)
+			return
+		}
+		lc.OnStop("db shards", func(context.Context) error { return db.Close(shards...) })
+		for i, shard := range shards {
+			m, err := migrate.NewShard(shard)
+			if err == nil {
+				err = m.EnsureCurrent(ctx)
+			}
+			if err != nil {
+				log.Error("shard schema check failed", "shard", i, "err", err)
+				return
+			}
+		}
+		log.Info("decision sharding enabled", "shards", len(shards))
+	}
+
+	// Init Redis. Not fatal: after a short wait the circuit breaker keeps
+	// requests on the DB until Redis becomes reachable.
+	redisCache := cache.NewRedisCache(cfg)
+	lc.OnStop("redis", func(context.Context) error { return redisCache.Close() })
+	redisCtx, cancelRedis := context.WithTimeout(startCtx, redisStartupWait)
+	// ping the client directly so startup failures do not trip the breaker
+	err = app.Retry(redisCtx, log, "redis", func() error { return redisCache.Client.Ping(redisCtx).Err() })
+	cancelRedis()
+	if err != nil {
+		log.Warn("redis unavailable, starting in degraded mode", "err", err, "breaker", redisCache.BreakerState().String())
+	}
 
 	// Inject logger into app context
 	appCtx := app.New(database, redisCache, log)
+	appCtx.Shards = repository.NewShardRouter(shards...)
+	appCtx.Shards.SetStickyWindow(cfg.DB.ReplicaSticky)
+	appCtx.Retry = &repository.RetryPolicy{
+		Attempts:  cfg.DB.RetryAttempts,
+		BaseDelay: cfg.DB.RetryBaseDelay,
+		MaxDelay:  cfg.DB.RetryMaxDelay,
+	}
+	appCtx.PageSize = cfg.Explore.PageSize
+
+	// Read replicas (optional): "liked you" queries go to them, writes and
+	// mutual checks stay on the primaries.
+	var replicas [][]*gorm.DB
+	err = app.Retry(startCtx, log, "db replicas", func() (err error) {
+		replicas, err = db.NewReplicas(cfg)
+		return err
+	})
+	if err != nil {
+		log.Error("failed to init db replicas", "err", err)
+		return
+	}
+	for i, group := range replicas {
+		appCtx.Shards.SetReplicas(i, group...)
+	}
+	if len(replicas) > 0 {
+		lc.OnStop("db replicas", func(context.Context) error {
+			var all []*gorm.DB
+			for _, group := range replicas {
+				all = append(all, group...)
+			}
+			return db.Close(all...)
+		})
+		log.Info("read replicas enabled", "groups", len(replicas), "sticky", cfg.DB.ReplicaSticky)
+	}
+
+	// Connection pool usage of every database, also in the db_pool_* metrics
+	if cfg.DB.PoolStatsInterval > 0 {
+		lc.Go("db pool stats", func(ctx context.Context) error {
+			db.WatchPoolStats(ctx, log, cfg.DB.PoolStatsInterval)
+			return nil
+		})
+	}
+
+	// Pagination token keys: without them tokens only work on this instance
+	keys, err := pagination.ParseKeys(cfg.Pagination.Keys)
+	if err != nil {
+		log.Error("invalid PAGINATION_KEYS", "err", err)
+		return
+	}
+	if len(keys) == 0 {
+		log.Warn("PAGINATION_KEYS not set, signing pagination tokens with a random key")
+		appCtx.Tokens = pagination.NewRandomCodec(cfg.Pagination.TokenTTL)
+	} else if appCtx.Tokens, err = pagination.NewCodec(cfg.Pagination.TokenTTL, keys...); err != nil {
+		log.Error("failed to init pagination tokens", "err", err)
+		return
+	}
 
 	registrars := []server.Registrar{
 		explore.NewRegistrar(appCtx),
 	}
 
-	if cfg.App.ENV == "development" {
-		if err := db.SeedTestData(database); err != nil {
-			log.Error("failed to seed: %v", err)
+	// Access token issuer for AuthService; without a signing key the
+	// service is only left out when auth is disabled anyway.
+	if appCtx.Issuer, err = auth.NewIssuer(cfg); err != nil {
+		if !cfg.Auth.Disabled {
+			log.Error("failed to init token issuer", "err", err)
+			return
 		}
+		log.Warn("AuthService disabled", "reason", err)
+	} else {
+		registrars = append(registrars, authsvc.NewRegistrar(appCtx))
 	}
 
-	addr := cfg.GRPC.Host + ":" + cfg.GRPC.Port
-	log.Info("starting gRPC server", "addr", addr)
+	// SeedTestData writes decisions to the main DB only, so it is skipped when sharded
+	if cfg.App.ENV == "development" && len(cfg.DB.ShardDSNs) == 0 {
+		if err := db.SeedTestData(database); err != nil {
+			log.Error("failed to seed", "err", err)
+		}
+	}
 
-	if err := server.StartGRPCServer(cfg, registrars...); err != nil {
+	grpcServer, err := server.NewGRPCServer(cfg, registrars...)
+	if err != nil {
 		log.Error("failed to start gRPC server", "err", err)
+		return
+	}
+
+	// HTTP endpoints: Prometheus /metrics, the optional /livez and /readyz
+	// for Kubernetes and the optional admin /loglevel, sharing a listener
+	// when the addresses match.
+	// Added before the gRPC server so they stop after it: /readyz reports
+	// 503 and metrics stay scrapeable while calls drain.
+	muxes := map[string]*http.ServeMux{}
+	muxFor := func(addr string) *http.ServeMux {
+		if muxes[addr] == nil {
+			muxes[addr] = http.NewServeMux()
+		}
+		return muxes[addr]
+	}
+	if cfg.Metrics.Addr != "" {
+		muxFor(cfg.Metrics.Addr).Handle("/metrics", metrics.Handler())
+	}
+	if cfg.Health.HTTPAddr != "" {
+		healthHandler := grpcServer.Health().HTTPHandler()
+		muxFor(cfg.Health.HTTPAddr).Handle("/livez", healthHandler)
+		muxFor(cfg.Health.HTTPAddr).Handle("/readyz", healthHandler)
+	}
+	if cfg.Admin.HTTPAddr != "" {
+		muxFor(cfg.Admin.HTTPAddr).Handle("/loglevel", logger.LevelHandler())
+	}
+	for addr, mux := range muxes {
+		hs := server.NewHTTPServer(addr, mux)
+		log.Info("starting HTTP server", "addr", addr)
+		lc.Add("http "+addr, hs.ListenAndServe, hs.Shutdown)
+	}
+
+	log.Info("starting gRPC server", "addr", grpcServer.Addr().String())
+	lc.Add("grpc server", grpcServer.Serve, grpcServer.Shutdown)
+
+	if err := lc.Run(); err != nil {
+		log.Error("shutdown with errors", "err", err)
+		return
 	}
+	log.Info("shutdown complete")
 }
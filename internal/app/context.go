@@ -1,7 +1,10 @@
 package app
 
 import (
+	"github.com/oggyb/muzz-exercise/internal/auth"
 	"github.com/oggyb/muzz-exercise/internal/cache"
+	"github.com/oggyb/muzz-exercise/internal/repository"
+	"github.com/oggyb/muzz-exercise/internal/utils/pagination"
 	"gorm.io/gorm"
 	"log/slog"
 )
@@ -11,6 +14,23 @@ type AppContext struct {
 	DB         *gorm.DB
 	RedisCache *cache.RedisCache
 	Logger     *slog.Logger
+
+	// Shards routes decisions to their database. Nil means everything is on DB.
+	Shards *repository.ShardRouter
+
+	// Tokens signs pagination tokens. Nil means a random per-process key.
+	Tokens *pagination.Codec
+
+	// Retry handles transient database errors in the decision repository.
+	// Nil means repository.DefaultRetryPolicy.
+	Retry *repository.RetryPolicy
+
+	// PageSize is the number of likers per list page. 0 means
+	// explore.DefaultPageSize.
+	PageSize int
+
+	// Issuer signs access tokens for AuthService.
+	Issuer *auth.Issuer
 }
 
 // New creates a new AppContext
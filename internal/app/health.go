@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/oggyb/muzz-exercise/internal/server"
+)
+
+// DatabaseProbe pings the main database and every decision shard. It is
+// critical: no service works without them.
+func (a *AppContext) DatabaseProbe() server.Probe {
+	dbs := []*gorm.DB{a.DB}
+	if a.Shards != nil {
+		for _, shard := range a.Shards.All() {
+			if shard != a.DB {
+				dbs = append(dbs, shard)
+			}
+		}
+	}
+	return server.Probe{
+		Name:     "database",
+		Critical: true,
+		Check: func(ctx context.Context) error {
+			var errs []error
+			for i, d := range dbs {
+				sqlDB, err := d.DB()
+				if err == nil {
+					err = sqlDB.PingContext(ctx)
+				}
+				if err != nil {
+					errs = append(errs, fmt.Errorf("db %d: %w", i, err))
+				}
+			}
+			return errors.Join(errs...)
+		},
+	}
+}
+
+// RedisProbe pings Redis directly, so the result reflects Redis itself
+// rather than the circuit breaker, whose state is shown next to it in
+// /readyz. It is not critical: the server keeps answering from the
+// database while Redis is down.
+func (a *AppContext) RedisProbe() server.Probe {
+	return server.Probe{
+		Name: "redis",
+		Check: func(ctx context.Context) error {
+			return a.RedisCache.Client.Ping(ctx).Err()
+		},
+		Detail: func() string {
+			return "breaker " + a.RedisCache.BreakerState().String()
+		},
+	}
+}
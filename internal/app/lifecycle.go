@@ -0,0 +1,199 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Lifecycle owns process shutdown: it turns SIGINT/SIGTERM into a
+// cancelled context and stops components in reverse start order.
+//
+// Behavior:
+//   - Context() is cancelled on the first signal; a second signal kills
+//     the process with the default handler.
+//   - Components registered with Add/Go/OnStop are stopped last-in,
+//     first-out, so a server is drained before the pools it uses close.
+//   - The whole shutdown shares one drain timeout; a stop func must give up
+//     when its context is done (e.g. force-close remaining streams).
+//   - A component that exits on its own with an error also triggers shutdown
+//     and is returned by Run.
+type Lifecycle struct {
+	log     *slog.Logger
+	timeout time.Duration
+
+	ctx        context.Context
+	stopSignal context.CancelFunc
+
+	mu     sync.Mutex
+	hooks  []stopHook
+	failed chan error
+	once   sync.Once
+}
+
+type stopHook struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// NewLifecycle starts listening for SIGINT/SIGTERM. drainTimeout bounds Shutdown.
+func NewLifecycle(log *slog.Logger, drainTimeout time.Duration) *Lifecycle {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	return &Lifecycle{
+		log:        log,
+		timeout:    drainTimeout,
+		ctx:        ctx,
+		stopSignal: stop,
+		failed:     make(chan error, 1),
+	}
+}
+
+// Context is cancelled when shutdown is requested. Use it for startup work
+// (connection retries, schema checks) so a signal aborts it.
+func (l *Lifecycle) Context() context.Context {
+	return l.ctx
+}
+
+// OnStop registers fn to run during Shutdown.
+//
+// Example:
+//
+//	lc.OnStop("redis", func(context.Context) error { return redisCache.Close() })
+func (l *Lifecycle) OnStop(name string, fn func(ctx context.Context) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, stopHook{name: name, stop: fn})
+}
+
+// Add runs the blocking start func in a goroutine; at shutdown stop is
+// called and Shutdown waits (up to the drain timeout) for start to return.
+//
+// Example:
+//
+//	lc.Add("grpc", srv.Serve, srv.Shutdown)
+func (l *Lifecycle) Add(name string, start func() error, stop func(ctx context.Context) error) {
+	done := make(chan struct{})
+	stopping := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := start()
+		select {
+		case <-stopping:
+		default:
+			if err == nil {
+				err = errors.New("exited")
+			}
+			l.fail(fmt.Errorf("%s: %w", name, err))
+		}
+	}()
+
+	l.OnStop(name, func(ctx context.Context) error {
+		close(stopping)
+		err := stop(ctx)
+		select {
+		case <-done:
+			return err
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		}
+	})
+}
+
+// Go runs a background worker until shutdown cancels its context.
+//
+// Example:
+//
+//	lc.Go("tls-reload", func(ctx context.Context) error { reloader.Watch(ctx, time.Minute); return nil })
+func (l *Lifecycle) Go(name string, fn func(ctx context.Context) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	l.Add(name, func() error { return fn(ctx) }, func(context.Context) error {
+		cancel()
+		return nil
+	})
+}
+
+func (l *Lifecycle) fail(err error) {
+	select {
+	case l.failed <- err:
+	default:
+	}
+}
+
+// Run blocks until a signal arrives or a component fails, then shuts down.
+// It returns the component failure, if any, joined with shutdown errors.
+func (l *Lifecycle) Run() error {
+	var cause error
+	select {
+	case <-l.ctx.Done():
+		l.log.Info("shutdown requested")
+	case cause = <-l.failed:
+		l.log.Error("component failed, shutting down", "err", cause)
+	}
+	return errors.Join(cause, l.Shutdown())
+}
+
+// Shutdown stops every registered component, newest first, within the
+// drain timeout. Only the first call does anything.
+func (l *Lifecycle) Shutdown() error {
+	var errs []error
+	l.once.Do(func() {
+		// restore default signal handling: a second Ctrl-C exits immediately
+		l.stopSignal()
+
+		ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+		defer cancel()
+
+		l.mu.Lock()
+		hooks := l.hooks
+		l.mu.Unlock()
+
+		for i := len(hooks) - 1; i >= 0; i-- {
+			h := hooks[i]
+			start := time.Now()
+			if err := h.stop(ctx); err != nil {
+				l.log.Error("stop failed", "name", h.name, "err", err)
+				errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+				continue
+			}
+			l.log.Info("stopped", "name", h.name, "took", time.Since(start))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// Retry calls fn until it succeeds or ctx is done, waiting with exponential
+// backoff (starting at 200ms, capped at 5s) between attempts. Use it for
+// dependencies that may come up after this process, e.g. in docker compose.
+//
+// Example:
+//
+//	err := app.Retry(ctx, log, "database", func() (err error) { database, err = db.NewDB(cfg); return err })
+func Retry(ctx context.Context, log *slog.Logger, name string, fn func() error) error {
+	const (
+		initialBackoff = 200 * time.Millisecond
+		maxBackoff     = 5 * time.Second
+	)
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		log.Warn("dependency not ready, retrying", "dependency", name, "attempt", attempt, "in", backoff, "err", err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s: %w (last error: %v)", name, ctx.Err(), err)
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
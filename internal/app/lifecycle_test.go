@@ -0,0 +1,86 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oggyb/muzz-exercise/internal/app"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// TestLifecycleStopsInReverseOrder checks that a failing component triggers
+// shutdown and that everything is stopped newest first.
+func TestLifecycleStopsInReverseOrder(t *testing.T) {
+	lc := app.NewLifecycle(discard, time.Second)
+
+	var order []string
+	lc.OnStop("db", func(context.Context) error {
+		order = append(order, "db")
+		return nil
+	})
+	lc.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		order = append(order, "worker")
+		return nil
+	})
+	boom := errors.New("boom")
+	lc.Add("server", func() error { return boom }, func(context.Context) error {
+		order = append(order, "server")
+		return nil
+	})
+
+	err := lc.Run()
+	assert.ErrorIs(t, err, boom)
+	assert.Equal(t, []string{"server", "worker", "db"}, order)
+
+	// Shutdown only runs once
+	require.NoError(t, lc.Shutdown())
+	assert.Len(t, order, 3)
+}
+
+// TestLifecycleDrainTimeout checks a component that does not stop in time
+// is reported and does not block the remaining stops.
+func TestLifecycleDrainTimeout(t *testing.T) {
+	lc := app.NewLifecycle(discard, 50*time.Millisecond)
+
+	closed := false
+	lc.OnStop("db", func(context.Context) error {
+		closed = true
+		return nil
+	})
+	hang := make(chan struct{})
+	defer close(hang)
+	lc.Add("server", func() error { <-hang; return nil }, func(context.Context) error { return nil })
+
+	err := lc.Shutdown()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, closed)
+}
+
+// TestRetryBacksOffUntilSuccess checks Retry keeps trying and gives up with
+// the last error once ctx is done.
+func TestRetryBacksOffUntilSuccess(t *testing.T) {
+	attempts := 0
+	err := app.Retry(context.Background(), discard, "dep", func() error {
+		if attempts++; attempts < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	err = app.Retry(ctx, discard, "dep", func() error { return errors.New("down") })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "down")
+}
@@ -0,0 +1,147 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/oggyb/muzz-exercise/internal/auth"
+	"github.com/oggyb/muzz-exercise/internal/config"
+)
+
+const secret = "test-secret-test-secret-test-secret"
+
+func hsToken(t *testing.T, sub string, exp time.Time) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   sub,
+		ExpiresAt: jwt.NewNumericDate(exp),
+	}).SignedString([]byte(secret))
+	require.NoError(t, err)
+	return token
+}
+
+func hsVerifier(t *testing.T) *auth.Verifier {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Auth.JWTSecret = secret
+	v, err := auth.NewVerifier(cfg)
+	require.NoError(t, err)
+	return v
+}
+
+func TestVerifyHS256(t *testing.T) {
+	v := hsVerifier(t)
+
+	id, err := v.Verify(hsToken(t, "42", time.Now().Add(time.Minute)))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), id)
+
+	for name, token := range map[string]string{
+		"expired":     hsToken(t, "42", time.Now().Add(-time.Hour)),
+		"bad subject": hsToken(t, "alice", time.Now().Add(time.Minute)),
+		"garbage":     "not.a.jwt",
+	} {
+		_, err := v.Verify(token)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken, name)
+	}
+
+	// "none" and other algorithms are never accepted
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.RegisteredClaims{
+		Subject: "42", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	_, err = v.Verify(unsigned)
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestVerifyRS256WithJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks, err := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "k1",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks, 0o600))
+
+	cfg := &config.Config{}
+	cfg.Auth.JWKSFile = path
+	cfg.Auth.Issuer = "muzz"
+	v, err := auth.NewVerifier(cfg)
+	require.NoError(t, err)
+
+	sign := func(kid, iss string) string {
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
+			Subject: "7", Issuer: iss, ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		})
+		tok.Header["kid"] = kid
+		s, err := tok.SignedString(key)
+		require.NoError(t, err)
+		return s
+	}
+
+	id, err := v.Verify(sign("k1", "muzz"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), id)
+
+	_, err = v.Verify(sign("k2", "muzz"))
+	assert.ErrorIs(t, err, auth.ErrInvalidToken, "unknown kid")
+	_, err = v.Verify(sign("k1", "other"))
+	assert.ErrorIs(t, err, auth.ErrInvalidToken, "wrong issuer")
+}
+
+func TestInterceptor(t *testing.T) {
+	interceptor := auth.NewInterceptor(hsVerifier(t), "/public.Service/")
+	unary := interceptor.Unary()
+
+	call := func(method, authorization string) (any, error) {
+		ctx := context.Background()
+		if authorization != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", authorization))
+		}
+		return unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, _ any) (any, error) {
+			id, _ := auth.UserID(ctx)
+			return id, nil
+		})
+	}
+
+	id, err := call("/explore.ExploreService/CountLikedYou", "Bearer "+hsToken(t, "5", time.Now().Add(time.Minute)))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), id)
+
+	_, err = call("/explore.ExploreService/CountLikedYou", "")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = call("/explore.ExploreService/CountLikedYou", "Bearer nope")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = call("/public.Service/Anything", "")
+	assert.NoError(t, err)
+}
+
+func TestRequireCaller(t *testing.T) {
+	ctx := auth.WithUserID(context.Background(), 1)
+	assert.NoError(t, auth.RequireCaller(ctx, 1))
+	assert.Equal(t, codes.PermissionDenied, status.Code(auth.RequireCaller(ctx, 2)))
+	assert.NoError(t, auth.RequireCaller(context.Background(), 2), "auth disabled")
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"context"
+
+	svcErr "github.com/oggyb/muzz-exercise/internal/errors"
+)
+
+type userIDKey struct{}
+
+// WithUserID returns a context carrying the authenticated caller.
+func WithUserID(ctx context.Context, userID uint64) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserID returns the authenticated caller, if the request carried a token.
+func UserID(ctx context.Context) (uint64, bool) {
+	id, ok := ctx.Value(userIDKey{}).(uint64)
+	return id, ok
+}
+
+// RequireCaller returns PermissionDenied unless the caller is userID.
+//
+// Behavior:
+//   - A context without a caller passes: that only happens when the auth
+//     interceptor is disabled (AUTH_DISABLED), since it rejects requests
+//     without a valid token otherwise.
+//
+// Example:
+//
+//	if err := auth.RequireCaller(ctx, recipientID); err != nil { return nil, err }
+func RequireCaller(ctx context.Context, userID uint64) error {
+	caller, ok := UserID(ctx)
+	if ok && caller != userID {
+		return svcErr.PermissionDenied(svcErr.ReasonNotOwner, "caller may only act on their own likes")
+	}
+	return nil
+}
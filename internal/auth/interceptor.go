@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	svcErr "github.com/oggyb/muzz-exercise/internal/errors"
+	"github.com/oggyb/muzz-exercise/internal/logger"
+)
+
+// Interceptor authenticates gRPC calls with a bearer JWT from the
+// "authorization" metadata and stores the caller's user ID in the context,
+// also adding it as "caller" to the request logger.
+type Interceptor struct {
+	verifier *Verifier
+	public   []string
+}
+
+// NewInterceptor creates an interceptor. publicMethods are full method
+// names ("/pkg.Service/Method") callable without a token; an entry ending
+// in "/" makes a whole service public.
+func NewInterceptor(v *Verifier, publicMethods ...string) *Interceptor {
+	return &Interceptor{verifier: v, public: publicMethods}
+}
+
+// Unary returns the unary server interceptor.
+func (i *Interceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := i.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream returns the stream server interceptor.
+func (i *Interceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := i.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (i *Interceptor) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if i.isPublic(method) {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, svcErr.Unauthenticated(svcErr.ReasonTokenMissing, "missing bearer token")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		token, ok = strings.CutPrefix(values[0], "bearer ")
+	}
+	if !ok || token == "" {
+		return nil, svcErr.Unauthenticated(svcErr.ReasonTokenMissing, "authorization must be a bearer token")
+	}
+
+	userID, err := i.verifier.Verify(token)
+	if err != nil {
+		return nil, svcErr.Unauthenticated(svcErr.ReasonTokenInvalid, err.Error())
+	}
+	ctx = logger.WithContext(ctx, "caller", userID)
+	return WithUserID(ctx, userID), nil
+}
+
+func (i *Interceptor) isPublic(method string) bool {
+	for _, p := range i.public {
+		if method == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(method, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// authStream overrides the context of a server stream.
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context { return s.ctx }
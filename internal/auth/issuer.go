@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/oggyb/muzz-exercise/internal/config"
+)
+
+// Issuer signs access tokens that a Verifier built from the same config accepts.
+//
+// Behavior:
+//   - RS256 with cfg.Auth.JWTPrivateKeyFile (kid = cfg.Auth.JWTKeyID) when set,
+//     HS256 with cfg.Auth.JWTSecret otherwise.
+//   - Tokens carry sub (user ID), iat, exp and the configured iss / aud.
+type Issuer struct {
+	method   jwt.SigningMethod
+	key      any
+	keyID    string
+	issuer   string
+	audience string
+	ttl      time.Duration
+	refresh  time.Duration
+	now      func() time.Time
+}
+
+// NewIssuer builds an Issuer from cfg.Auth.
+func NewIssuer(cfg *config.Config) (*Issuer, error) {
+	i := &Issuer{
+		issuer:   cfg.Auth.Issuer,
+		audience: cfg.Auth.Audience,
+		ttl:      cfg.Auth.AccessTTL,
+		refresh:  cfg.Auth.RefreshTTL,
+		now:      time.Now,
+	}
+	if i.ttl <= 0 {
+		i.ttl = 15 * time.Minute
+	}
+	if i.refresh <= 0 {
+		i.refresh = 30 * 24 * time.Hour
+	}
+
+	switch {
+	case cfg.Auth.JWTPrivateKeyFile != "":
+		pemBytes, err := os.ReadFile(cfg.Auth.JWTPrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read jwt private key: %w", err)
+		}
+		var key *rsa.PrivateKey
+		if key, err = jwt.ParseRSAPrivateKeyFromPEM(pemBytes); err != nil {
+			return nil, fmt.Errorf("parse jwt private key: %w", err)
+		}
+		i.method, i.key, i.keyID = jwt.SigningMethodRS256, key, cfg.Auth.JWTKeyID
+	case cfg.Auth.JWTSecret != "":
+		i.method, i.key = jwt.SigningMethodHS256, []byte(cfg.Auth.JWTSecret)
+	default:
+		return nil, errors.New("auth: no signing key configured (AUTH_JWT_PRIVATE_KEY_FILE or AUTH_JWT_SECRET)")
+	}
+	return i, nil
+}
+
+// Issue returns a signed access token for userID and its expiry.
+//
+// Example:
+//
+//	token, exp, err := issuer.Issue(42)
+func (i *Issuer) Issue(userID uint64) (string, time.Time, error) {
+	now := i.now()
+	exp := now.Add(i.ttl)
+	claims := jwt.RegisteredClaims{
+		Subject:   strconv.FormatUint(userID, 10),
+		Issuer:    i.issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(exp),
+	}
+	if i.audience != "" {
+		claims.Audience = jwt.ClaimStrings{i.audience}
+	}
+
+	token := jwt.NewWithClaims(i.method, claims)
+	if i.keyID != "" {
+		token.Header["kid"] = i.keyID
+	}
+	signed, err := token.SignedString(i.key)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("sign access token: %w", err)
+	}
+	return signed, exp, nil
+}
+
+// NewRefreshToken returns a random opaque refresh token, the hash to store
+// it under and its expiry. Refresh tokens are not JWTs: they are only valid
+// while their session exists (see RefreshTokenHash).
+func (i *Issuer) NewRefreshToken() (token, hash string, expires time.Time, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", time.Time{}, fmt.Errorf("generate refresh token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, RefreshTokenHash(token), i.now().Add(i.refresh), nil
+}
+
+// RefreshTTL is how long a refresh session lives.
+func (i *Issuer) RefreshTTL() time.Duration {
+	return i.refresh
+}
+
+// RefreshTokenHash is the storage key of a refresh token, so a leaked
+// session store does not leak usable tokens.
+func RefreshTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/oggyb/muzz-exercise/internal/config"
+)
+
+// ErrInvalidToken is returned (wrapped) for any JWT that fails verification.
+var ErrInvalidToken = errors.New("invalid token")
+
+// leeway tolerates small clock differences between issuer and server.
+const leeway = 30 * time.Second
+
+// Verifier validates access tokens and extracts the caller's user ID.
+//
+// Accepted tokens:
+//   - HS256, signed with cfg.Auth.JWTSecret.
+//   - RS256, signed by the key in cfg.Auth.JWTPublicKeyFile (or the public
+//     half of cfg.Auth.JWTPrivateKeyFile) or by the JWKS key matching the
+//     token's kid header.
+//   - "sub" holds the decimal user ID; "exp" is required; "iss" and "aud"
+//     are checked when configured.
+type Verifier struct {
+	secret    []byte
+	publicKey *rsa.PublicKey
+	jwks      map[string]*rsa.PublicKey
+	parser    *jwt.Parser
+}
+
+// NewVerifier builds a Verifier from cfg.Auth. At least one key source is required.
+func NewVerifier(cfg *config.Config) (*Verifier, error) {
+	v := &Verifier{}
+	var methods []string
+
+	if cfg.Auth.JWTSecret != "" {
+		v.secret = []byte(cfg.Auth.JWTSecret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.Auth.JWTPublicKeyFile != "" {
+		pemBytes, err := os.ReadFile(cfg.Auth.JWTPublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read jwt public key: %w", err)
+		}
+		if v.publicKey, err = jwt.ParseRSAPublicKeyFromPEM(pemBytes); err != nil {
+			return nil, fmt.Errorf("parse jwt public key: %w", err)
+		}
+	}
+	if v.publicKey == nil && cfg.Auth.JWTPrivateKeyFile != "" {
+		// accept the tokens this server issues itself (see Issuer)
+		pemBytes, err := os.ReadFile(cfg.Auth.JWTPrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read jwt private key: %w", err)
+		}
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse jwt private key: %w", err)
+		}
+		v.publicKey = &key.PublicKey
+	}
+	if cfg.Auth.JWKSFile != "" {
+		var err error
+		if v.jwks, err = LoadJWKS(cfg.Auth.JWKSFile); err != nil {
+			return nil, err
+		}
+	}
+	if v.publicKey != nil || len(v.jwks) > 0 {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("auth: no JWT key configured (AUTH_JWT_SECRET, AUTH_JWT_PUBLIC_KEY_FILE or AUTH_JWKS_FILE)")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+	}
+	if cfg.Auth.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Auth.Issuer))
+	}
+	if cfg.Auth.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Auth.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+	return v, nil
+}
+
+// Verify checks token and returns the user ID from its subject.
+//
+// Example:
+//
+//	userID, err := v.Verify("eyJhbGciOiJIUzI1NiIs...")
+func (v *Verifier) Verify(token string) (uint64, error) {
+	var claims jwt.RegisteredClaims
+	if _, err := v.parser.ParseWithClaims(token, &claims, v.key); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || userID == 0 {
+		return 0, fmt.Errorf("%w: subject is not a user ID", ErrInvalidToken)
+	}
+	return userID, nil
+}
+
+// key picks the verification key for a token; the algorithm itself was
+// already checked against the configured ones by the parser.
+func (v *Verifier) key(t *jwt.Token) (any, error) {
+	switch t.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return v.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		if kid, _ := t.Header["kid"].(string); kid != "" && v.jwks != nil {
+			if k, ok := v.jwks[kid]; ok {
+				return k, nil
+			}
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		if v.publicKey != nil {
+			return v.publicKey, nil
+		}
+		return nil, errors.New("token has no key id")
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+}
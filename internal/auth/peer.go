@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// ClientIdentity returns the identity of an mTLS client, taken from the
+// subject alternative names of its verified certificate.
+//
+// Behavior:
+//   - Prefers the first URI SAN (e.g. spiffe://cluster/ns/app/sa/api),
+//     then the first DNS name, then the first email address.
+//   - Returns false for plaintext connections, clients without a
+//     certificate and certificates without SANs; only certificates that
+//     verified against the client CA count.
+//
+// Example:
+//
+//	if id, ok := auth.ClientIdentity(ctx); ok { log.Info("call", "client", id) }
+func ClientIdentity(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return certIdentity(info.State.VerifiedChains[0][0])
+}
+
+func certIdentity(cert *x509.Certificate) (string, bool) {
+	switch {
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String(), true
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0], true
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0], true
+	}
+	return "", false
+}
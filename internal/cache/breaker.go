@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of calling Redis while the breaker is open.
+var ErrCircuitOpen = errors.New("redis circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// StateClosed lets every call through.
+	StateClosed BreakerState = iota
+	// StateOpen rejects every call until the cooldown elapses.
+	StateOpen
+	// StateHalfOpen lets a single probe call through to test recovery.
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker trips after a number of consecutive failures and
+// short-circuits calls for a cooldown period.
+//
+// Behavior:
+//   - closed → open after `threshold` consecutive failures.
+//   - open → half-open once `cooldown` has elapsed; one probe is allowed.
+//   - half-open → closed if the probe succeeds, back to open if it fails.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	probing   bool
+	openedAt  time.Time
+	threshold int
+	cooldown  time.Duration
+
+	now      func() time.Time
+	onChange func(from, to BreakerState)
+}
+
+// NewCircuitBreaker creates a breaker. Non-positive values fall back to
+// a threshold of 5 failures and a cooldown of 10s.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if cooldown <= 0 {
+		cooldown = 10 * time.Second
+	}
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// OnStateChange registers a callback invoked on every state transition.
+// The callback runs while the breaker lock is held and must not call back into it.
+func (b *CircuitBreaker) OnStateChange(fn func(from, to BreakerState)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onChange = fn
+}
+
+// State returns the current breaker state.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Allow reports whether a call may proceed. It returns ErrCircuitOpen
+// while the breaker is open or a half-open probe is already in flight.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.setState(StateHalfOpen)
+		b.probing = true
+		return nil
+	case StateHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Success records a successful call.
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	if b.state != StateClosed {
+		b.setState(StateClosed)
+	}
+}
+
+// Failure records a failed call.
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	switch b.state {
+	case StateHalfOpen:
+		b.trip()
+	case StateClosed:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.trip()
+		}
+	}
+}
+
+// Release ends a call whose outcome says nothing about the backend, such
+// as one cancelled by the caller. A half-open probe slot is freed so the
+// next call can probe again; the state is left unchanged.
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *CircuitBreaker) trip() {
+	b.failures = 0
+	b.openedAt = b.now()
+	b.setState(StateOpen)
+}
+
+func (b *CircuitBreaker) setState(to BreakerState) {
+	from := b.state
+	b.state = to
+	if b.onChange != nil && from != to {
+		b.onChange(from, to)
+	}
+}
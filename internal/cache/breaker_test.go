@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestBreaker returns a breaker driven by a fake clock
+func newTestBreaker(threshold int, cooldown time.Duration) (*CircuitBreaker, *time.Time) {
+	now := time.Unix(0, 0)
+	b := NewCircuitBreaker(threshold, cooldown)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestBreaker_TripsAfterConsecutiveFailures(t *testing.T) {
+	b, _ := newTestBreaker(3, time.Second)
+
+	b.Failure()
+	b.Failure()
+	b.Success() // resets the streak
+	b.Failure()
+	b.Failure()
+	assert.Equal(t, StateClosed, b.State())
+
+	b.Failure()
+	assert.Equal(t, StateOpen, b.State())
+	assert.ErrorIs(t, b.Allow(), ErrCircuitOpen)
+}
+
+func TestBreaker_HalfOpenProbe(t *testing.T) {
+	b, now := newTestBreaker(1, time.Second)
+
+	var transitions []string
+	b.OnStateChange(func(from, to BreakerState) {
+		transitions = append(transitions, from.String()+"->"+to.String())
+	})
+
+	b.Failure()
+	assert.Equal(t, StateOpen, b.State())
+
+	// cooldown elapsed → a single probe is allowed
+	*now = now.Add(time.Second)
+	assert.NoError(t, b.Allow())
+	assert.Equal(t, StateHalfOpen, b.State())
+	assert.ErrorIs(t, b.Allow(), ErrCircuitOpen)
+
+	// failed probe re-opens
+	b.Failure()
+	assert.Equal(t, StateOpen, b.State())
+
+	// successful probe closes
+	*now = now.Add(time.Second)
+	assert.NoError(t, b.Allow())
+	b.Success()
+	assert.Equal(t, StateClosed, b.State())
+	assert.NoError(t, b.Allow())
+
+	assert.Equal(t, []string{
+		"closed->open",
+		"open->half_open",
+		"half_open->open",
+		"open->half_open",
+		"half_open->closed",
+	}, transitions)
+}
@@ -8,13 +8,29 @@ import (
 	"time"
 
 	"github.com/oggyb/muzz-exercise/internal/config"
+	"github.com/oggyb/muzz-exercise/internal/logger"
+	"github.com/oggyb/muzz-exercise/internal/metrics"
+	"github.com/oggyb/muzz-exercise/internal/tracing"
 	"github.com/redis/go-redis/v9"
 )
 
+// RedisCache wraps a Redis client with a circuit breaker.
+// While the breaker is open every call fails fast with ErrCircuitOpen,
+// so callers can fall back to the database without waiting on timeouts.
 type RedisCache struct {
-	Client *redis.Client
+	Client  *redis.Client
+	breaker *CircuitBreaker
+
+	likeCountPrefix string
+	likeCountTTL    time.Duration
 }
 
+// Defaults for like counters when cfg.Cache leaves them unset.
+const (
+	DefaultLikeCountPrefix = "likes:count"
+	DefaultLikeCountTTL    = time.Hour
+)
+
 // NewRedisCache initializes Redis client from config.
 // Only Addr is mandatory, Password/DB are optional.
 func NewRedisCache(cfg *config.Config) *RedisCache {
@@ -27,53 +43,159 @@ func NewRedisCache(cfg *config.Config) *RedisCache {
 	if cfg.Redis.DB != 0 {
 		opts.DB = cfg.Redis.DB
 	}
-	return &RedisCache{Client: redis.NewClient(opts)}
+	if cfg.Redis.Timeout > 0 {
+		opts.DialTimeout = cfg.Redis.Timeout
+		opts.ReadTimeout = cfg.Redis.Timeout
+		opts.WriteTimeout = cfg.Redis.Timeout
+	}
+
+	breaker := NewCircuitBreaker(cfg.Redis.BreakerThreshold, cfg.Redis.BreakerCooldown)
+	breaker.OnStateChange(func(from, to BreakerState) {
+		logger.Warn("redis circuit breaker state changed", "from", from.String(), "to", to.String())
+		metrics.SetRedisBreakerState(int(to))
+	})
+
+	client := redis.NewClient(opts)
+	client.AddHook(tracing.RedisHook{})
+
+	c := &RedisCache{
+		Client:          client,
+		breaker:         breaker,
+		likeCountPrefix: cfg.Cache.LikeCountPrefix,
+		likeCountTTL:    cfg.Cache.LikeCountTTL,
+	}
+	if c.likeCountPrefix == "" {
+		c.likeCountPrefix = DefaultLikeCountPrefix
+	}
+	if c.likeCountTTL <= 0 {
+		c.likeCountTTL = DefaultLikeCountTTL
+	}
+	return c
+}
+
+// BreakerState returns the current state of the Redis circuit breaker.
+func (c *RedisCache) BreakerState() BreakerState {
+	return c.breaker.State()
+}
+
+// do runs fn through the circuit breaker.
+// Cache misses (redis.Nil) and caller cancellations do not count as failures.
+func (c *RedisCache) do(ctx context.Context, fn func() error) error {
+	if err := c.breaker.Allow(); err != nil {
+		return err
+	}
+	err := fn()
+	switch {
+	case err == nil, errors.Is(err, redis.Nil):
+		c.breaker.Success()
+	case ctx.Err() != nil:
+		// caller gave up; says nothing about Redis health, but a
+		// half-open probe must hand its slot back
+		c.breaker.Release()
+	default:
+		c.breaker.Failure()
+	}
+	return err
 }
 
 func (c *RedisCache) Ping(ctx context.Context) error {
-	return c.Client.Ping(ctx).Err()
+	return c.do(ctx, func() error {
+		return c.Client.Ping(ctx).Err()
+	})
+}
+
+// Close releases the connection pool. Calls after Close fail.
+func (c *RedisCache) Close() error {
+	return c.Client.Close()
 }
 
 func (c *RedisCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	return c.Client.Set(ctx, key, value, ttl).Err()
+	return c.do(ctx, func() error {
+		return c.Client.Set(ctx, key, value, ttl).Err()
+	})
 }
 
 func (c *RedisCache) Get(ctx context.Context, key string) (string, error) {
-	return c.Client.Get(ctx, key).Result()
+	var val string
+	err := c.do(ctx, func() (err error) {
+		val, err = c.Client.Get(ctx, key).Result()
+		return err
+	})
+	return val, err
 }
 
 func (c *RedisCache) Del(ctx context.Context, key string) error {
-	return c.Client.Del(ctx, key).Err()
+	return c.do(ctx, func() error {
+		return c.Client.Del(ctx, key).Err()
+	})
+}
+
+// GetDel returns the value of key and deletes it atomically (redis.Nil if absent).
+func (c *RedisCache) GetDel(ctx context.Context, key string) (string, error) {
+	var val string
+	err := c.do(ctx, func() (err error) {
+		val, err = c.Client.GetDel(ctx, key).Result()
+		return err
+	})
+	return val, err
 }
 
 func (c *RedisCache) Incr(ctx context.Context, key string) (int64, error) {
-	return c.Client.Incr(ctx, key).Result()
+	var n int64
+	err := c.do(ctx, func() (err error) {
+		n, err = c.Client.Incr(ctx, key).Result()
+		return err
+	})
+	return n, err
 }
 
 func (c *RedisCache) Decr(ctx context.Context, key string) (int64, error) {
-	return c.Client.Decr(ctx, key).Result()
+	var n int64
+	err := c.do(ctx, func() (err error) {
+		n, err = c.Client.Decr(ctx, key).Result()
+		return err
+	})
+	return n, err
+}
+
+func (c *RedisCache) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return c.do(ctx, func() error {
+		return c.Client.Expire(ctx, key, ttl).Err()
+	})
 }
 
 // KeyForLikeCount generates Redis key for a user's like count
+// (cfg.Cache.LikeCountPrefix, "likes:count" by default).
 func (c *RedisCache) KeyForLikeCount(userID uint64) string {
-	return fmt.Sprintf("likes:count:%d", userID)
+	return fmt.Sprintf("%s:%d", c.likeCountPrefix, userID)
+}
+
+// LikeCountTTL is how long a like count stays cached after the user was
+// last active.
+func (c *RedisCache) LikeCountTTL() time.Duration {
+	return c.likeCountTTL
+}
+
+// KeyForRefreshSession generates the Redis key of a refresh session.
+// Only a hash of the refresh token is stored, never the token itself.
+func (c *RedisCache) KeyForRefreshSession(tokenHash string) string {
+	return "auth:refresh:" + tokenHash
 }
 
 func (c *RedisCache) UpdateLikeCount(ctx context.Context, userID uint64, count int64) error {
-	key := fmt.Sprintf("likes:count:%d", userID)
 	// Always refresh TTL when updating
-	return c.Client.Set(ctx, key, count, time.Hour).Err()
+	return c.Set(ctx, c.KeyForLikeCount(userID), count, c.likeCountTTL)
 }
 
 func (c *RedisCache) GetLikeCount(ctx context.Context, userID uint64) (int64, error) {
-	key := fmt.Sprintf("likes:count:%d", userID)
-	val, err := c.Client.Get(ctx, key).Result()
+	key := c.KeyForLikeCount(userID)
+	val, err := c.Get(ctx, key)
 	if errors.Is(err, redis.Nil) {
 		return 0, nil // cache miss
 	} else if err != nil {
 		return 0, err
 	}
 	// refresh TTL on access
-	_ = c.Client.Expire(ctx, key, time.Hour).Err()
+	_ = c.Expire(ctx, key, c.likeCountTTL)
 	return strconv.ParseInt(val, 10, 64)
 }
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oggyb/muzz-exercise/internal/config"
+)
+
+func TestLikeCountKeyAndTTLFromConfig(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cfg := &config.Config{}
+	cfg.Redis.Addr = mr.Addr()
+
+	// unset → defaults
+	c := NewRedisCache(cfg)
+	assert.Equal(t, "likes:count:42", c.KeyForLikeCount(42))
+	assert.Equal(t, DefaultLikeCountTTL, c.LikeCountTTL())
+	require.NoError(t, c.Close())
+
+	cfg.Cache.LikeCountPrefix = "explore:likes"
+	cfg.Cache.LikeCountTTL = 10 * time.Minute
+	c = NewRedisCache(cfg)
+	t.Cleanup(func() { c.Close() })
+
+	require.NoError(t, c.UpdateLikeCount(context.Background(), 42, 7))
+	assert.Equal(t, 10*time.Minute, mr.TTL("explore:likes:42"))
+
+	n, err := c.GetLikeCount(context.Background(), 42)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), n)
+}
+
+func TestCancelledProbeReleasesBreaker(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cfg := &config.Config{}
+	cfg.Redis.Addr = mr.Addr()
+	c := NewRedisCache(cfg)
+	t.Cleanup(func() { c.Close() })
+
+	b, now := newTestBreaker(1, time.Second)
+	c.breaker = b
+	b.Failure()
+	*now = now.Add(time.Second)
+
+	// the half-open probe is cancelled by its caller
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Error(t, c.Ping(ctx))
+	assert.Equal(t, StateHalfOpen, c.BreakerState())
+
+	// the next call probes Redis and closes the breaker
+	require.NoError(t, c.Ping(context.Background()))
+	assert.Equal(t, StateClosed, c.BreakerState())
+}
@@ -1,97 +1,375 @@
 package config
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
-	"strconv"
 	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
+// Config is the service configuration. Load builds it from, lowest to
+// highest precedence: the defaults, a YAML file using the yaml keys below,
+// environment variables and command-line flags (see bindings for the names).
 type Config struct {
 	App struct {
-		ENV string
-	}
+		ENV string `yaml:"env"`
+		// StartupTimeout bounds retrying the database at startup.
+		StartupTimeout time.Duration `yaml:"startup_timeout"`
+		// ShutdownTimeout bounds draining in-flight calls on SIGTERM.
+		ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	} `yaml:"app"`
 	Log struct {
-		Level     string
-		Format    string
-		Component string
-		Source    bool
-	}
+		Level     string `yaml:"level"`
+		Format    string `yaml:"format"`
+		Component string `yaml:"component"`
+		Source    bool   `yaml:"source"`
+
+		// LevelFile, when set, is re-read on SIGHUP for a new level.
+		LevelFile string `yaml:"level_file"`
+
+		// Attributes whose values are replaced by [REDACTED] or hashed.
+		RedactKeys []string `yaml:"redact_keys"`
+		HashKeys   []string `yaml:"hash_keys"`
+
+		// Sampling: per message, SampleInitial records per SampleTick,
+		// then every SampleThereafter-th. SampleInitial 0 → off.
+		SampleInitial    int           `yaml:"sample_initial"`
+		SampleThereafter int           `yaml:"sample_thereafter"`
+		SampleTick       time.Duration `yaml:"sample_tick"`
+	} `yaml:"log"`
+
+	Admin struct {
+		// HTTPAddr serves /loglevel; empty → off. Keep it off public
+		// networks: it is not authenticated.
+		HTTPAddr string `yaml:"http_addr"`
+	} `yaml:"admin"`
 
 	DB struct {
-		DSN      string
-		Host     string
-		Port     string
-		User     string
-		Password string
-		Name     string
-	}
+		Driver     string `yaml:"driver"`      // mysql, postgres or sqlite; empty → inferred from DSN
+		SQLitePath string `yaml:"sqlite_path"` // database file used by the sqlite driver
+		DSN        string `yaml:"dsn"`
+		Host       string `yaml:"host"`
+		Port       string `yaml:"port"`
+		User       string `yaml:"user"`
+		Password   string `yaml:"password"`
+		Name       string `yaml:"name"`
+
+		// ShardDSNs, when set, spreads decisions over several databases
+		// by recipient ID (see repository.ShardRouter). DSN above keeps users.
+		ShardDSNs []string `yaml:"shard_dsns"`
+
+		// ReplicaDSNs are read replicas serving the "liked you" queries.
+		// Unsharded: every entry replicates the main DB. Sharded: entry i
+		// replicates shard i, with several replicas separated by "|".
+		ReplicaDSNs []string `yaml:"replica_dsns"`
+		// ReplicaSticky keeps a user's reads on the primary for this long
+		// after they write, so they always see their own decision.
+		ReplicaSticky time.Duration `yaml:"replica_sticky"`
+
+		// SQL logging (see db.SQLLogger): LogLevel is silent, error, warn
+		// or info; statements slower than SlowQuery log at warn.
+		LogLevel        string        `yaml:"log_level"`
+		SlowQuery       time.Duration `yaml:"slow_query"`
+		LogRedactParams bool          `yaml:"log_redact_params"`
+
+		// Retries of transient errors (deadlocks, lock wait timeouts) in
+		// the decision repository; RetryAttempts counts the first try.
+		RetryAttempts  int           `yaml:"retry_attempts"`
+		RetryBaseDelay time.Duration `yaml:"retry_base_delay"`
+		RetryMaxDelay  time.Duration `yaml:"retry_max_delay"`
+
+		// Connection pool of every database (main, shards, replicas); 0
+		// keeps the database/sql default. PoolStatsInterval is how often
+		// pool usage is logged; 0 → off.
+		MaxOpenConns      int           `yaml:"max_open_conns"`
+		MaxIdleConns      int           `yaml:"max_idle_conns"`
+		ConnMaxLifetime   time.Duration `yaml:"conn_max_lifetime"`
+		PoolStatsInterval time.Duration `yaml:"pool_stats_interval"`
+	} `yaml:"db"`
 
 	Redis struct {
-		Addr     string
-		Password string
-		DB       int
-	}
+		Addr     string        `yaml:"addr"`
+		Password string        `yaml:"password"`
+		DB       int           `yaml:"db"`
+		Timeout  time.Duration `yaml:"timeout"`
+
+		// Circuit breaker: trips after BreakerThreshold consecutive
+		// failures and stays open for BreakerCooldown.
+		BreakerThreshold int           `yaml:"breaker_threshold"`
+		BreakerCooldown  time.Duration `yaml:"breaker_cooldown"`
+	} `yaml:"redis"`
+
+	Cache struct {
+		// Redis like counters: key LikeCountPrefix:<user ID>, expiring
+		// LikeCountTTL after the user was last active.
+		LikeCountPrefix string        `yaml:"like_count_prefix"`
+		LikeCountTTL    time.Duration `yaml:"like_count_ttl"`
+	} `yaml:"cache"`
+
+	Explore struct {
+		// PageSize is the number of likers per ListLikedYou page.
+		PageSize int `yaml:"page_size"`
+	} `yaml:"explore"`
 
 	GRPC struct {
-		Host string
-		Port string
-	}
+		Host string `yaml:"host"`
+		Port string `yaml:"port"`
+
+		// Timeout bounds unary calls sent without a deadline; MethodTimeouts
+		// overrides it per "/pkg.Service/Method=dur" or "/pkg.Service/=dur".
+		Timeout        time.Duration `yaml:"timeout"`
+		MethodTimeouts []string      `yaml:"method_timeouts"`
+
+		// TLS is off unless CertFile and KeyFile are set. With ClientCAFile
+		// clients must present a certificate signed by it (mTLS).
+		TLS struct {
+			CertFile     string        `yaml:"cert_file"`
+			KeyFile      string        `yaml:"key_file"`
+			ClientCAFile string        `yaml:"client_ca_file"`
+			ClientAuth   string        `yaml:"client_auth"`  // none, request or require; empty → require with a CA
+			ReloadEvery  time.Duration `yaml:"reload_every"` // how often the files are checked for changes
+		} `yaml:"tls"`
+	} `yaml:"grpc"`
+
+	Health struct {
+		Interval time.Duration `yaml:"interval"` // how often dependency probes run
+		Timeout  time.Duration `yaml:"timeout"`  // per-probe timeout
+		// HTTPAddr serves /livez and /readyz over HTTP when set (e.g. ":8081").
+		HTTPAddr string `yaml:"http_addr"`
+	} `yaml:"health"`
+
+	Metrics struct {
+		// Addr serves Prometheus /metrics over HTTP; empty turns it off.
+		// It may equal Health.HTTPAddr to share one listener.
+		Addr string `yaml:"addr"`
+	} `yaml:"metrics"`
+
+	Tracing struct {
+		Exporter    string  `yaml:"exporter"`     // none, otlp or stdout
+		Endpoint    string  `yaml:"endpoint"`     // OTLP gRPC collector, e.g. otel-collector:4317
+		Insecure    bool    `yaml:"insecure"`     // plaintext to the collector
+		SampleRatio float64 `yaml:"sample_ratio"` // share of new traces sampled, 0..1
+		ServiceName string  `yaml:"service_name"`
+	} `yaml:"tracing"`
+
+	Auth struct {
+		// Disabled turns JWT checks off (local development only).
+		Disabled bool `yaml:"disabled"`
+		// Verification keys; any combination may be set.
+		JWTSecret        string `yaml:"jwt_secret"`          // HS256 shared secret
+		JWTPublicKeyFile string `yaml:"jwt_public_key_file"` // RS256 public key, PEM
+		JWKSFile         string `yaml:"jwks_file"`           // local JWKS file with RS256 keys, selected by kid
+		// Optional iss / aud claims every token must carry.
+		Issuer   string `yaml:"issuer"`
+		Audience string `yaml:"audience"`
+
+		// Token issuing (AuthService). RS256 when a private key is set,
+		// HS256 with JWTSecret otherwise.
+		JWTPrivateKeyFile string        `yaml:"jwt_private_key_file"` // RS256 private key, PEM
+		JWTKeyID          string        `yaml:"jwt_key_id"`           // kid header of issued RS256 tokens
+		AccessTTL         time.Duration `yaml:"access_ttl"`
+		RefreshTTL        time.Duration `yaml:"refresh_ttl"`
+	} `yaml:"auth"`
+
+	Pagination struct {
+		// Keys are "id:secret" HMAC keys; the first signs, all verify.
+		// Empty → a random per-process key (tokens break on restart).
+		Keys     []string      `yaml:"keys"`
+		TokenTTL time.Duration `yaml:"token_ttl"`
+	} `yaml:"pagination"`
 }
 
-func New() *Config {
+// Default returns the configuration used when nothing is set.
+func Default() *Config {
 	cfg := &Config{}
 
 	// App
-	cfg.App.ENV = getEnvDefault("APP_ENV", "development")
+	cfg.App.ENV = "development"
+	cfg.App.StartupTimeout = 30 * time.Second
+	cfg.App.ShutdownTimeout = 20 * time.Second
 	// Logger
-	cfg.Log.Level = getEnvDefault("LOG_LEVEL", "info")
-	cfg.Log.Format = getEnvDefault("LOG_FORMAT", "text")
-	cfg.Log.Component = getEnvDefault("LOG_COMPONENT", "grpc_server")
-	cfg.Log.Source = isTruthy(os.Getenv("LOG_SOURCE"))
-
-	// Database
-	cfg.DB.DSN = os.Getenv("MYSQL_DSN")
-	if cfg.DB.DSN == "" {
-		cfg.DB.Host = getEnvDefault("DB_HOST", "localhost")
-		cfg.DB.Port = getEnvDefault("DB_PORT", "3306")
-		cfg.DB.User = getEnvDefault("DB_USER", "root")
-		cfg.DB.Password = getEnvDefault("DB_PASSWORD", "root")
-		cfg.DB.Name = getEnvDefault("DB_NAME", "muzz")
-
-		cfg.DB.DSN = fmt.Sprintf(
-			"%s:%s@tcp(%s:%s)/%s?parseTime=true&charset=utf8mb4&loc=UTC",
-			cfg.DB.User, cfg.DB.Password, cfg.DB.Host, cfg.DB.Port, cfg.DB.Name,
-		)
-	}
+	cfg.Log.Level = "info"
+	cfg.Log.Format = "text"
+	cfg.Log.Component = "grpc_server"
+	cfg.Log.RedactKeys = []string{"password", "secret", "access_token", "refresh_token", "authorization"}
+	cfg.Log.HashKeys = []string{"email"}
+	cfg.Log.SampleThereafter = 100
+	cfg.Log.SampleTick = time.Second
+
+	// Database: Host, Port, User, Password and Name only build the DSN when
+	// none is given (see finish).
+	cfg.DB.SQLitePath = "muzz.db"
+	cfg.DB.ReplicaSticky = 5 * time.Second
+	cfg.DB.LogLevel = "warn"
+	cfg.DB.SlowQuery = 200 * time.Millisecond
+	cfg.DB.RetryAttempts = 3
+	cfg.DB.RetryBaseDelay = 25 * time.Millisecond
+	cfg.DB.RetryMaxDelay = time.Second
+	cfg.DB.MaxOpenConns = 25
+	cfg.DB.MaxIdleConns = 10
+	cfg.DB.ConnMaxLifetime = 30 * time.Minute
+	cfg.DB.PoolStatsInterval = time.Minute
 
 	// Redis
-	cfg.Redis.Addr = getEnvDefault("REDIS_ADDR", "localhost:6379")
-	cfg.Redis.Password = getEnvDefault("REDIS_PASSWORD", "")
-	if dbStr := getEnvDefault("REDIS_DB", "0"); dbStr != "" {
-		if dbInt, err := strconv.Atoi(dbStr); err == nil {
-			cfg.Redis.DB = dbInt
-		}
-	}
+	cfg.Redis.Addr = "localhost:6379"
+	cfg.Redis.Timeout = 250 * time.Millisecond
+	cfg.Redis.BreakerThreshold = 5
+	cfg.Redis.BreakerCooldown = 10 * time.Second
+
+	// Cache
+	cfg.Cache.LikeCountPrefix = "likes:count"
+	cfg.Cache.LikeCountTTL = time.Hour
+
+	// Explore
+	cfg.Explore.PageSize = 5
 
 	// gRPC
-	cfg.GRPC.Host = getEnvDefault("GRPC_HOST", "127.0.0.1")
-	cfg.GRPC.Port = getEnvDefault("GRPC_PORT", "50051")
+	cfg.GRPC.Host = "127.0.0.1"
+	cfg.GRPC.Port = "50051"
+	cfg.GRPC.Timeout = 10 * time.Second
+	cfg.GRPC.TLS.ReloadEvery = time.Minute
+
+	// Health checks
+	cfg.Health.Interval = 5 * time.Second
+	cfg.Health.Timeout = time.Second
+
+	// Metrics
+	cfg.Metrics.Addr = ":9090"
+
+	// Tracing
+	cfg.Tracing.Exporter = "none"
+	cfg.Tracing.SampleRatio = 1
+	cfg.Tracing.ServiceName = "muzz-explore"
+
+	// Auth
+	cfg.Auth.AccessTTL = 15 * time.Minute
+	cfg.Auth.RefreshTTL = 30 * 24 * time.Hour
+
+	// Pagination tokens
+	cfg.Pagination.TokenTTL = 24 * time.Hour
 
 	return cfg
 }
 
-func getEnvDefault(k, def string) string {
-	if v := strings.TrimSpace(os.Getenv(k)); v != "" {
-		return v
+// New returns the defaults overridden by environment variables. Invalid
+// values are ignored and nothing is validated; commands use Load, New is
+// kept for tests.
+func New() *Config {
+	cfg := Default()
+	_ = applyEnv(cfg.bindings())
+	cfg.finish()
+	return cfg
+}
+
+// Load builds the configuration from the defaults, the YAML file named by
+// --config (or CONFIG_FILE), environment variables and the flags in args,
+// each overriding the previous, then validates it under profile.
+//
+// Behavior:
+//   - the config flags are registered on fs, which may hold the command's
+//     own flags; fs is parsed with args
+//   - blank environment variables count as unset, except lists, where an
+//     empty value clears the list
+//   - secrets can also be read from a file: DB_PASSWORD_FILE, or
+//     --db-password-file, for DB_PASSWORD (see bindings)
+//   - every invalid value, unknown file key and failed check is reported,
+//     joined into one error; the Config is still returned so it can be
+//     printed
+//
+// Example:
+//
+//	cfg, err := config.Load(flag.CommandLine, os.Args[1:], config.ProfileServer)
+//	if err != nil {
+//		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+//		os.Exit(2)
+//	}
+func Load(fs *flag.FlagSet, args []string, profile Profile) (*Config, error) {
+	cfg := Default()
+	bindings := cfg.bindings()
+
+	file := fs.String("config", "", "YAML config file ($CONFIG_FILE)")
+	flags := registerFlags(fs, bindings)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	if *file == "" {
+		*file = strings.TrimSpace(os.Getenv("CONFIG_FILE"))
+	}
+	if *file != "" {
+		if err := cfg.loadFile(*file); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	errs = append(errs, applyEnv(bindings)...)
+	errs = append(errs, flags.apply()...)
+	cfg.finish()
+
+	errs = append(errs, cfg.Validate(profile))
+	return cfg, errors.Join(errs...)
+}
+
+// loadFile decodes the YAML file at path over cfg. Keys it does not know
+// are errors, so typos do not go unnoticed.
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// finish normalizes case-insensitive values and builds the DSN from its
+// parts when none is set: a postgres:// URL with DB_DRIVER=postgres, a
+// MySQL DSN otherwise.
+func (c *Config) finish() {
+	c.DB.Driver = strings.ToLower(c.DB.Driver)
+	c.DB.LogLevel = strings.ToLower(c.DB.LogLevel)
+	c.Tracing.Exporter = strings.ToLower(c.Tracing.Exporter)
+
+	if c.DB.DSN != "" || c.DB.Driver == "sqlite" {
+		return
+	}
+	setDefault(&c.DB.Host, "localhost")
+	setDefault(&c.DB.Name, "muzz")
+	if c.DB.Driver == "postgres" {
+		setDefault(&c.DB.Port, "5432")
+		setDefault(&c.DB.User, "postgres")
+		setDefault(&c.DB.Password, "postgres")
+		c.DB.DSN = (&url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(c.DB.User, c.DB.Password),
+			Host:   net.JoinHostPort(c.DB.Host, c.DB.Port),
+			Path:   "/" + c.DB.Name,
+		}).String()
+		return
 	}
-	return def
+	setDefault(&c.DB.Port, "3306")
+	setDefault(&c.DB.User, "root")
+	setDefault(&c.DB.Password, "root")
+	c.DB.DSN = fmt.Sprintf(
+		"%s:%s@tcp(%s:%s)/%s?parseTime=true&charset=utf8mb4&loc=UTC",
+		c.DB.User, c.DB.Password, c.DB.Host, c.DB.Port, c.DB.Name,
+	)
 }
 
-func isTruthy(v string) bool {
-	switch strings.ToLower(strings.TrimSpace(v)) {
-	case "1", "true", "yes", "y", "on":
-		return true
+func setDefault(p *string, def string) {
+	if *p == "" {
+		*p = def
 	}
-	return false
 }
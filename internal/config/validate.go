@@ -0,0 +1,173 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oggyb/muzz-exercise/internal/utils/pagination"
+)
+
+// Profile selects which settings Validate checks.
+type Profile int
+
+const (
+	// ProfileServer checks everything cmd/server uses.
+	ProfileServer Profile = iota
+	// ProfileTool checks only the app, logger and database settings used
+	// by the command line tools (migrate, seed, backfill), so they run
+	// without a JWT secret, TLS files or a gRPC port.
+	ProfileTool
+)
+
+// Validate checks the settings used under profile and returns all
+// problems joined into one error, each prefixed with its environment
+// variable, or nil.
+func (c *Config) Validate(profile Profile) error {
+	var v validator
+
+	// App
+	v.positive("STARTUP_TIMEOUT", c.App.StartupTimeout)
+	v.positive("SHUTDOWN_TIMEOUT", c.App.ShutdownTimeout)
+
+	// Logger
+	v.oneOf("LOG_LEVEL", strings.ToLower(c.Log.Level), "debug", "info", "warn", "warning", "error")
+	v.oneOf("LOG_FORMAT", strings.ToLower(c.Log.Format), "text", "json")
+	v.check(c.Log.SampleInitial >= 0, "LOG_SAMPLE_INITIAL", "must not be negative, got %d", c.Log.SampleInitial)
+	v.check(c.Log.SampleThereafter >= 0, "LOG_SAMPLE_THEREAFTER", "must not be negative, got %d", c.Log.SampleThereafter)
+	if c.Log.SampleInitial > 0 {
+		v.positive("LOG_SAMPLE_TICK", c.Log.SampleTick)
+	}
+
+	// Database
+	v.oneOf("DB_DRIVER", c.DB.Driver, "", "mysql", "postgres", "sqlite")
+	if len(c.DB.ShardDSNs) > 0 && len(c.DB.ReplicaDSNs) > 0 {
+		v.check(len(c.DB.ReplicaDSNs) == len(c.DB.ShardDSNs), "DB_REPLICA_DSNS",
+			"has %d entries, want one per shard (%d)", len(c.DB.ReplicaDSNs), len(c.DB.ShardDSNs))
+	}
+	v.notNegative("DB_REPLICA_STICKY", c.DB.ReplicaSticky)
+	v.oneOf("DB_LOG_LEVEL", c.DB.LogLevel, "", "silent", "off", "error", "warn", "warning", "info")
+	v.notNegative("DB_SLOW_QUERY_THRESHOLD", c.DB.SlowQuery)
+	v.check(c.DB.RetryAttempts >= 1, "DB_RETRY_ATTEMPTS", "must be at least 1, got %d", c.DB.RetryAttempts)
+	v.notNegative("DB_RETRY_BASE_DELAY", c.DB.RetryBaseDelay)
+	v.check(c.DB.RetryMaxDelay >= c.DB.RetryBaseDelay, "DB_RETRY_MAX_DELAY",
+		"must not be below DB_RETRY_BASE_DELAY (%s), got %s", c.DB.RetryBaseDelay, c.DB.RetryMaxDelay)
+	v.check(c.DB.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS", "must not be negative, got %d", c.DB.MaxOpenConns)
+	v.check(c.DB.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS", "must not be negative, got %d", c.DB.MaxIdleConns)
+	v.check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "DB_MAX_IDLE_CONNS",
+		"must not exceed DB_MAX_OPEN_CONNS (%d), got %d", c.DB.MaxOpenConns, c.DB.MaxIdleConns)
+	v.notNegative("DB_CONN_MAX_LIFETIME", c.DB.ConnMaxLifetime)
+	v.notNegative("DB_POOL_STATS_INTERVAL", c.DB.PoolStatsInterval)
+
+	if profile == ProfileTool {
+		return errors.Join(v.errs...)
+	}
+
+	// Redis
+	v.check(c.Redis.Addr != "", "REDIS_ADDR", "must be set")
+	v.check(c.Redis.DB >= 0, "REDIS_DB", "must not be negative, got %d", c.Redis.DB)
+	v.positive("REDIS_TIMEOUT", c.Redis.Timeout)
+	v.check(c.Redis.BreakerThreshold >= 1, "REDIS_BREAKER_THRESHOLD", "must be at least 1, got %d", c.Redis.BreakerThreshold)
+	v.positive("REDIS_BREAKER_COOLDOWN", c.Redis.BreakerCooldown)
+
+	// Cache and explore
+	v.check(c.Cache.LikeCountPrefix != "", "CACHE_LIKE_COUNT_PREFIX", "must be set")
+	v.positive("CACHE_LIKE_COUNT_TTL", c.Cache.LikeCountTTL)
+	v.check(c.Explore.PageSize >= 1, "EXPLORE_PAGE_SIZE", "must be at least 1, got %d", c.Explore.PageSize)
+
+	// gRPC
+	port, err := strconv.Atoi(c.GRPC.Port)
+	v.check(err == nil && port >= 0 && port <= 65535, "GRPC_PORT", "want a port number, got %q", c.GRPC.Port)
+	v.notNegative("GRPC_DEFAULT_TIMEOUT", c.GRPC.Timeout)
+	for _, e := range c.GRPC.MethodTimeouts {
+		method, raw, ok := strings.Cut(e, "=")
+		d, err := time.ParseDuration(strings.TrimSpace(raw))
+		v.check(ok && strings.HasPrefix(strings.TrimSpace(method), "/") && err == nil && d >= 0,
+			"GRPC_METHOD_TIMEOUTS", "invalid entry %q, want /pkg.Service/Method=duration", e)
+	}
+	tls := c.GRPC.TLS
+	v.check((tls.CertFile == "") == (tls.KeyFile == ""), "GRPC_TLS_CERT_FILE", "must be set together with GRPC_TLS_KEY_FILE")
+	v.check(tls.ClientCAFile == "" || tls.CertFile != "", "GRPC_TLS_CLIENT_CA_FILE", "requires GRPC_TLS_CERT_FILE")
+	v.oneOf("GRPC_TLS_CLIENT_AUTH", strings.ToLower(tls.ClientAuth), "", "none", "request", "require")
+	v.exists("GRPC_TLS_CERT_FILE", tls.CertFile)
+	v.exists("GRPC_TLS_KEY_FILE", tls.KeyFile)
+	v.exists("GRPC_TLS_CLIENT_CA_FILE", tls.ClientCAFile)
+	if tls.CertFile != "" {
+		v.positive("GRPC_TLS_RELOAD_INTERVAL", tls.ReloadEvery)
+	}
+
+	// Health checks
+	v.positive("HEALTH_INTERVAL", c.Health.Interval)
+	v.positive("HEALTH_TIMEOUT", c.Health.Timeout)
+
+	// Tracing
+	v.oneOf("TRACING_EXPORTER", c.Tracing.Exporter, "", "none", "otlp", "stdout")
+	v.check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO",
+		"must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+
+	// Auth
+	v.check(c.Auth.Disabled || c.Auth.JWTSecret != "" || c.Auth.JWTPublicKeyFile != "" ||
+		c.Auth.JWKSFile != "" || c.Auth.JWTPrivateKeyFile != "",
+		"AUTH_JWT_SECRET", "required unless AUTH_JWT_PUBLIC_KEY_FILE, AUTH_JWT_PRIVATE_KEY_FILE, AUTH_JWKS_FILE or AUTH_DISABLED is set")
+	v.exists("AUTH_JWT_PUBLIC_KEY_FILE", c.Auth.JWTPublicKeyFile)
+	v.exists("AUTH_JWKS_FILE", c.Auth.JWKSFile)
+	v.exists("AUTH_JWT_PRIVATE_KEY_FILE", c.Auth.JWTPrivateKeyFile)
+	v.positive("AUTH_ACCESS_TTL", c.Auth.AccessTTL)
+	v.positive("AUTH_REFRESH_TTL", c.Auth.RefreshTTL)
+
+	// Pagination tokens
+	if _, err := pagination.ParseKeys(c.Pagination.Keys); err != nil {
+		v.add("PAGINATION_KEYS", "%v", err)
+	}
+	v.positive("PAGINATION_TOKEN_TTL", c.Pagination.TokenTTL)
+
+	return errors.Join(v.errs...)
+}
+
+// validator collects the problems found by Validate.
+type validator struct {
+	errs []error
+}
+
+func (v *validator) add(name, format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", name, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) check(ok bool, name, format string, args ...any) {
+	if !ok {
+		v.add(name, format, args...)
+	}
+}
+
+func (v *validator) positive(name string, d time.Duration) {
+	v.check(d > 0, name, "must be positive, got %s", d)
+}
+
+func (v *validator) notNegative(name string, d time.Duration) {
+	v.check(d >= 0, name, "must not be negative, got %s", d)
+}
+
+// oneOf checks value against allowed; an allowed "" is not listed in the
+// message.
+func (v *validator) oneOf(name, value string, allowed ...string) {
+	if slices.Contains(allowed, value) {
+		return
+	}
+	want := slices.DeleteFunc(slices.Clone(allowed), func(s string) bool { return s == "" })
+	v.add(name, "unknown value %q (want %s)", value, strings.Join(want, ", "))
+}
+
+// exists checks that a configured file can be found, so a typo fails at
+// startup rather than on first use.
+func (v *validator) exists(name, path string) {
+	if path == "" {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		v.add(name, "%v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+
+	"github.com/oggyb/muzz-exercise/internal/logger"
+)
+
+// SQLLogger writes gorm's logs through the request logger (see
+// logger.FromContext), so SQL lines share the app's format and carry the
+// request ID of the call that ran them.
+//
+// Behavior:
+//   - Level silent logs nothing; error logs failed statements at Error;
+//     warn adds statements slower than SlowThreshold at Warn; info adds
+//     every statement at Info.
+//   - gorm.ErrRecordNotFound is not an error here: the repositories
+//     expect it.
+//   - With RedactParams the SQL keeps its placeholders instead of the
+//     bound values, which may hold emails or password hashes.
+type SQLLogger struct {
+	// DB names the database in each line ("main", "shard0", ...).
+	DB            string
+	Level         gormlogger.LogLevel
+	SlowThreshold time.Duration
+	RedactParams  bool
+}
+
+// ParseSQLLogLevel maps silent, error, warn or info to a gorm log level.
+//
+// Example:
+//
+//	lvl, err := db.ParseSQLLogLevel("warn") // gormlogger.Warn
+func ParseSQLLogLevel(s string) (gormlogger.LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "silent", "off":
+		return gormlogger.Silent, nil
+	case "error":
+		return gormlogger.Error, nil
+	case "warn", "warning", "":
+		return gormlogger.Warn, nil
+	case "info":
+		return gormlogger.Info, nil
+	default:
+		return 0, fmt.Errorf("unknown sql log level %q (silent, error, warn or info)", s)
+	}
+}
+
+// LogMode implements gormlogger.Interface.
+func (l *SQLLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.Level = level
+	return &copied
+}
+
+// Info implements gormlogger.Interface.
+func (l *SQLLogger) Info(ctx context.Context, msg string, data ...any) {
+	if l.Level >= gormlogger.Info {
+		logger.FromContext(ctx).InfoContext(ctx, fmt.Sprintf(msg, data...), "db", l.DB)
+	}
+}
+
+// Warn implements gormlogger.Interface.
+func (l *SQLLogger) Warn(ctx context.Context, msg string, data ...any) {
+	if l.Level >= gormlogger.Warn {
+		logger.FromContext(ctx).WarnContext(ctx, fmt.Sprintf(msg, data...), "db", l.DB)
+	}
+}
+
+// Error implements gormlogger.Interface.
+func (l *SQLLogger) Error(ctx context.Context, msg string, data ...any) {
+	if l.Level >= gormlogger.Error {
+		logger.FromContext(ctx).ErrorContext(ctx, fmt.Sprintf(msg, data...), "db", l.DB)
+	}
+}
+
+// Trace implements gormlogger.Interface; gorm calls it after each statement.
+func (l *SQLLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.Level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	slow := l.SlowThreshold > 0 && elapsed > l.SlowThreshold
+
+	log := logger.FromContext(ctx)
+	file := utils.FileWithLineNum() // called here: it skips a fixed number of frames
+	attrs := func() []any {
+		sql, rows := fc()
+		return []any{
+			"db", l.DB,
+			"sql", sql,
+			"rows", rows,
+			"duration_ms", float64(elapsed.Microseconds()) / 1000,
+			"file", file,
+		}
+	}
+	switch {
+	case failed && l.Level >= gormlogger.Error:
+		log.ErrorContext(ctx, "sql failed", append(attrs(), "err", err)...)
+	case slow && l.Level >= gormlogger.Warn:
+		log.WarnContext(ctx, "slow sql", append(attrs(), "threshold", l.SlowThreshold.String())...)
+	case l.Level >= gormlogger.Info:
+		log.InfoContext(ctx, "sql", attrs()...)
+	}
+}
+
+// ParamsFilter implements gorm.ParamsFilter: with RedactParams, logged SQL
+// keeps its placeholders. Scan and Row bypass it (see redactRowParams).
+func (l *SQLLogger) ParamsFilter(_ context.Context, sql string, params ...any) (string, []any) {
+	if l.RedactParams {
+		return sql, nil
+	}
+	return sql, params
+}
+
+// redactedParamsKey marks a statement context whose SQL is logged without
+// its bound values.
+type redactedParamsKey struct{}
+
+var installRecorderFilter sync.Once
+
+// redactRowParams makes Scan and Row on database log their SQL with
+// placeholders too. gorm renders their SQL through its trace recorder,
+// which ignores SQLLogger.ParamsFilter and calls the process-wide
+// gormlogger.RecorderParamsFilter instead.
+//
+// Behavior:
+//   - A first callback in the row chain marks the statement context.
+//   - The process-wide filter, installed once, drops the values only of
+//     marked statements and leaves other databases as gorm would.
+func redactRowParams(database *gorm.DB) error {
+	installRecorderFilter.Do(func() {
+		next := gormlogger.RecorderParamsFilter
+		gormlogger.RecorderParamsFilter = func(ctx context.Context, sql string, params ...any) (string, []any) {
+			if ctx != nil && ctx.Value(redactedParamsKey{}) != nil {
+				return sql, nil
+			}
+			if next == nil {
+				return sql, params
+			}
+			return next(ctx, sql, params...)
+		}
+	})
+	return database.Callback().Row().Before("*").Register("db:redact_params", func(tx *gorm.DB) {
+		ctx := tx.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		tx.Statement.Context = context.WithValue(ctx, redactedParamsKey{}, true)
+	})
+}
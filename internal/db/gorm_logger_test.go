@@ -0,0 +1,111 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/oggyb/muzz-exercise/internal/config"
+	"github.com/oggyb/muzz-exercise/internal/logger"
+)
+
+// TestSQLLogger checks levels, the slow-query threshold and redaction of
+// the SQL lines written through the context logger.
+func TestSQLLogger(t *testing.T) {
+	open := func(t *testing.T, level string, slow time.Duration, redact bool) (context.Context, *bytes.Buffer, func(string) error, *gorm.DB) {
+		cfg := &config.Config{}
+		cfg.DB.Driver = DriverSQLite
+		cfg.DB.SQLitePath = filepath.Join(t.TempDir(), "muzz.db")
+		cfg.DB.LogLevel = level
+		cfg.DB.SlowQuery = slow
+		cfg.DB.LogRedactParams = redact
+		database, err := NewDB(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() { Close(database) })
+
+		var buf bytes.Buffer
+		ctx := logger.NewContext(context.Background(),
+			slog.New(slog.NewJSONHandler(&buf, nil)).With("request_id", "req-1"))
+		query := func(email string) error {
+			return database.WithContext(ctx).Exec("SELECT ? AS email", email).Error
+		}
+		return ctx, &buf, query, database
+	}
+	lines := func(buf *bytes.Buffer) []map[string]any {
+		var out []map[string]any
+		for _, raw := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+			if len(raw) == 0 {
+				continue
+			}
+			var line map[string]any
+			require.NoError(t, json.Unmarshal(raw, &line))
+			out = append(out, line)
+		}
+		return out
+	}
+
+	t.Run("info logs every statement", func(t *testing.T) {
+		_, buf, query, _ := open(t, "info", time.Hour, false)
+		buf.Reset()
+		_ = query("alice@example.com")
+		got := lines(buf)
+		require.Len(t, got, 1)
+		assert.Equal(t, "INFO", got[0]["level"])
+		assert.Equal(t, "main", got[0]["db"])
+		assert.Equal(t, "req-1", got[0]["request_id"])
+		assert.Contains(t, got[0]["sql"], "alice@example.com")
+		assert.Contains(t, got[0]["file"], "gorm_logger_test.go")
+	})
+
+	t.Run("warn skips fast statements", func(t *testing.T) {
+		_, buf, query, _ := open(t, "warn", time.Hour, false)
+		buf.Reset()
+		_ = query("alice@example.com")
+		assert.Empty(t, lines(buf))
+	})
+
+	t.Run("warn logs only slow statements, redacted", func(t *testing.T) {
+		ctx, buf, query, database := open(t, "warn", time.Nanosecond, true)
+		buf.Reset()
+		_ = query("alice@example.com")
+		got := lines(buf)
+		require.Len(t, got, 1)
+		assert.Equal(t, "WARN", got[0]["level"])
+		assert.Equal(t, "slow sql", got[0]["msg"])
+		assert.NotContains(t, got[0]["sql"], "alice@example.com")
+		assert.Contains(t, got[0]["sql"], "?")
+
+		// Scan logs through gorm's recorder
+		buf.Reset()
+		var email string
+		require.NoError(t, database.WithContext(ctx).Raw("SELECT ? AS email", "bob@example.com").Scan(&email).Error)
+		assert.NotContains(t, buf.String(), "bob@example.com")
+	})
+
+	t.Run("redaction stays on its database", func(t *testing.T) {
+		redactedCtx, redactedBuf, _, redacted := open(t, "info", time.Hour, true)
+		plainCtx, plainBuf, _, plain := open(t, "info", time.Hour, false)
+		scan := func(ctx context.Context, database *gorm.DB, email string) {
+			var got string
+			require.NoError(t, database.WithContext(ctx).Raw("SELECT ? AS email", email).Scan(&got).Error)
+		}
+
+		scan(redactedCtx, redacted, "bob@example.com")
+		scan(plainCtx, plain, "carol@example.com")
+		scan(redactedCtx, redacted, "dave@example.com")
+		assert.NotContains(t, redactedBuf.String(), "bob@example.com")
+		assert.NotContains(t, redactedBuf.String(), "dave@example.com")
+		assert.Contains(t, plainBuf.String(), "carol@example.com")
+	})
+
+	_, err := ParseSQLLogLevel("loud")
+	assert.Error(t, err)
+}
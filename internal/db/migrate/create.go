@@ -0,0 +1,71 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Dialects lists the dialect directories every migration must exist in.
+var Dialects = []string{"mysql", "postgres", "sqlite"}
+
+var nameCleanRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Create scaffolds empty up/down files for the next version in every
+// dialect directory under dir and returns the created paths.
+//
+// Example:
+//
+//	migrate.Create("internal/db/migrations", "add user stats")
+//	// → mysql/0002_add_user_stats.up.sql, mysql/0002_add_user_stats.down.sql, ...
+func Create(dir, name string) ([]string, error) {
+	name = strings.Trim(nameCleanRe.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, fmt.Errorf("migration name must contain letters or digits")
+	}
+
+	next, err := nextVersion(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []string
+	for _, dialect := range Dialects {
+		if err := os.MkdirAll(filepath.Join(dir, dialect), 0o755); err != nil {
+			return created, err
+		}
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(dir, dialect, fmt.Sprintf("%04d_%s.%s.sql", next, name, direction))
+			body := fmt.Sprintf("-- %04d_%s (%s, %s)\n", next, name, dialect, direction)
+			if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+				return created, err
+			}
+			created = append(created, path)
+		}
+	}
+	return created, nil
+}
+
+// nextVersion returns one more than the highest version found in any dialect directory.
+func nextVersion(dir string) (uint64, error) {
+	var highest uint64
+	for _, dialect := range Dialects {
+		entries, err := os.ReadDir(filepath.Join(dir, dialect))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		for _, e := range entries {
+			if match := fileRe.FindStringSubmatch(e.Name()); match != nil {
+				if v, _ := strconv.ParseUint(match[1], 10, 64); v > highest {
+					highest = v
+				}
+			}
+		}
+	}
+	return highest + 1, nil
+}
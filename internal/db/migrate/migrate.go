@@ -0,0 +1,395 @@
+// Package migrate applies the versioned SQL migrations embedded in
+// internal/db/migrations and tracks them in the schema_migrations table.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/oggyb/muzz-exercise/internal/db/migrations"
+)
+
+// ErrSchemaBehind is returned by EnsureCurrent when migrations are pending.
+var ErrSchemaBehind = errors.New("database schema is behind; run `migrate up`")
+
+// Migration is a single versioned schema change.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes whether a migration has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator runs migrations for the dialect of the given connection.
+//
+// Concurrency:
+//   - MySQL and PostgreSQL runs are serialized with a session-level
+//     advisory lock (GET_LOCK / pg_advisory_lock), so replicas starting
+//     together cannot apply the same migration twice.
+//   - SQLite has no advisory locks; each migration runs in its own
+//     transaction and re-checks schema_migrations before applying.
+type Migrator struct {
+	db          *gorm.DB
+	dialect     string
+	migrations  []Migration
+	LockTimeout time.Duration
+}
+
+// New creates a Migrator using the embedded migration files.
+func New(database *gorm.DB) (*Migrator, error) {
+	return NewFromFS(database, migrations.FS)
+}
+
+// NewShard creates a Migrator for a decisions shard, using the embedded
+// migrations under shard/.
+func NewShard(database *gorm.DB) (*Migrator, error) {
+	sub, err := fs.Sub(migrations.FS, "shard")
+	if err != nil {
+		return nil, err
+	}
+	return NewFromFS(database, sub)
+}
+
+// NewFromFS creates a Migrator reading migrations from fsys/<dialect>/.
+func NewFromFS(database *gorm.DB, fsys fs.FS) (*Migrator, error) {
+	dialect := database.Dialector.Name()
+	list, err := Load(fsys, dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:          database,
+		dialect:     dialect,
+		migrations:  list,
+		LockTimeout: 30 * time.Second,
+	}, nil
+}
+
+// Up applies all pending migrations in version order and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			ok, err := m.apply(conn, mig, true)
+			if err != nil {
+				return err
+			}
+			if ok {
+				done = append(done, mig)
+			}
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the last `steps` applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]uint64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for i := 0; i < steps && i < len(versions); i++ {
+			mig, ok := m.find(versions[i])
+			if !ok {
+				return fmt.Errorf("applied migration %d has no down file in this binary", versions[i])
+			}
+			ok, err := m.apply(conn, mig, false)
+			if err != nil {
+				return err
+			}
+			if ok {
+				done = append(done, mig)
+			}
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists all known migrations with their applied state.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.ensureTable(m.db.WithContext(ctx)); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		at, ok := applied[mig.Version]
+		out = append(out, Status{Migration: mig, Applied: ok, AppliedAt: at})
+	}
+	return out, nil
+}
+
+// Pending returns the migrations that have not been applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, s := range statuses {
+		if !s.Applied {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// EnsureCurrent returns ErrSchemaBehind if any migration is pending.
+// A schema ahead of this binary (unknown applied versions) is accepted,
+// so an older release can keep running during a rollout.
+func (m *Migrator) EnsureCurrent(ctx context.Context) error {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		versions := make([]string, len(pending))
+		for i, p := range pending {
+			versions[i] = fmt.Sprintf("%04d_%s", p.Version, p.Name)
+		}
+		return fmt.Errorf("%w (pending: %s)", ErrSchemaBehind, strings.Join(versions, ", "))
+	}
+	return nil
+}
+
+// apply runs one migration in a transaction. It returns false when another
+// process applied (or reverted) it first.
+//
+// Note: MySQL commits DDL implicitly, so a failing statement in a
+// multi-statement MySQL migration can leave earlier statements applied.
+func (m *Migrator) apply(conn *gorm.DB, mig Migration, up bool) (bool, error) {
+	changed := false
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Raw("SELECT COUNT(*) FROM schema_migrations WHERE version = ?", mig.Version).
+			Scan(&count).Error; err != nil {
+			return err
+		}
+		if (count > 0) == up {
+			return nil // already in the desired state
+		}
+
+		script := mig.Up
+		if !up {
+			script = mig.Down
+		}
+		for _, stmt := range splitStatements(script) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+			}
+		}
+
+		if up {
+			if err := tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				mig.Version, mig.Name, time.Now().UTC()).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", mig.Version).Error; err != nil {
+				return err
+			}
+		}
+		changed = true
+		return nil
+	})
+	return changed, err
+}
+
+// withLock pins a single connection, takes the migration lock on it and
+// makes sure the schema_migrations table exists before running fn.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		unlock, err := m.lock(conn)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		if err := m.ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// advisoryLockKey identifies the migration lock for pg_advisory_lock.
+const advisoryLockKey int64 = 0x6d757a7a // "muzz"
+
+func (m *Migrator) lock(conn *gorm.DB) (unlock func(), err error) {
+	switch m.dialect {
+	case "mysql":
+		var got int
+		timeout := int(m.LockTimeout / time.Second)
+		if err := conn.Raw("SELECT GET_LOCK('schema_migrations', ?)", timeout).Scan(&got).Error; err != nil {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if got != 1 {
+			return nil, fmt.Errorf("timed out after %s waiting for migration lock", m.LockTimeout)
+		}
+		return func() { conn.Exec("SELECT RELEASE_LOCK('schema_migrations')") }, nil
+	case "postgres":
+		if err := conn.Exec(fmt.Sprintf("SET lock_timeout = %d", m.LockTimeout.Milliseconds())).Error; err != nil {
+			return nil, err
+		}
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", advisoryLockKey).Error; err != nil {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		return func() {
+			conn.Exec("SELECT pg_advisory_unlock(?)", advisoryLockKey)
+			conn.Exec("RESET lock_timeout")
+		}, nil
+	default:
+		return func() {}, nil
+	}
+}
+
+func (m *Migrator) ensureTable(conn *gorm.DB) error {
+	tsType := "TIMESTAMP"
+	switch m.dialect {
+	case "mysql":
+		tsType = "DATETIME(3)"
+	case "postgres":
+		tsType = "TIMESTAMPTZ"
+	}
+	return conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT       NOT NULL PRIMARY KEY,
+		name       VARCHAR(255) NOT NULL,
+		applied_at ` + tsType + ` NOT NULL
+	)`).Error
+}
+
+func (m *Migrator) applied(conn *gorm.DB) (map[uint64]time.Time, error) {
+	var rows []struct {
+		Version   uint64
+		AppliedAt time.Time
+	}
+	if err := conn.Raw("SELECT version, applied_at FROM schema_migrations").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	out := make(map[uint64]time.Time, len(rows))
+	for _, r := range rows {
+		out[r.Version] = r.AppliedAt
+	}
+	return out, nil
+}
+
+func (m *Migrator) find(version uint64) (Migration, bool) {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig, true
+		}
+	}
+	return Migration{}, false
+}
+
+var fileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load reads and validates the migrations in fsys/<dialect>/.
+// Every version needs both an up and a down file.
+func Load(fsys fs.FS, dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %q: %w", dialect, err)
+	}
+
+	byVersion := map[uint64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		match := fileRe.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s/%s", dialect, e.Name())
+		}
+		version, _ := strconv.ParseUint(match[1], 10, 64)
+		body, err := fs.ReadFile(fsys, dialect+"/"+e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		} else if mig.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, match[2])
+		}
+		if match[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %s/%04d_%s needs both up and down files", dialect, mig.Version, mig.Name)
+		}
+		list = append(list, *mig)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// splitStatements splits a script into statements on semicolons at the
+// end of a line. Full-line `--` comments are dropped. Semicolons inside
+// string literals at a line end are not supported.
+func splitStatements(script string) []string {
+	var (
+		stmts []string
+		cur   strings.Builder
+	)
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		cur.WriteString(line)
+		cur.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(cur.String()), ";"))
+			cur.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(cur.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
@@ -0,0 +1,177 @@
+package migrate_test
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/oggyb/muzz-exercise/internal/config"
+	"github.com/oggyb/muzz-exercise/internal/db"
+	"github.com/oggyb/muzz-exercise/internal/db/migrate"
+	"github.com/oggyb/muzz-exercise/internal/db/migrations"
+)
+
+// openSQLite opens a fresh SQLite file through db.NewDB, exactly as the server does
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.DB.Driver = db.DriverSQLite
+	cfg.DB.SQLitePath = filepath.Join(t.TempDir(), "muzz.db")
+	database, err := db.NewDB(cfg)
+	require.NoError(t, err)
+	return database
+}
+
+func TestUpStatusDown(t *testing.T) {
+	ctx := context.Background()
+	database := openSQLite(t)
+
+	m, err := migrate.New(database)
+	require.NoError(t, err)
+
+	// fresh database is behind
+	assert.ErrorIs(t, m.EnsureCurrent(ctx), migrate.ErrSchemaBehind)
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, applied)
+	require.NoError(t, m.EnsureCurrent(ctx))
+
+	// running again is a no-op
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	// the migrated schema matches the gorm models
+	migrator := database.Migrator()
+	assert.True(t, migrator.HasIndex(&db.Decision{}, "idx_recipient_liked_updated_actor"))
+	assert.True(t, migrator.HasIndex(&db.Decision{}, "idx_actor_recipient_liked"))
+	user := db.User{Username: "u1", Email: "u1@test.com", PasswordHash: "x", Gender: "male"}
+	require.NoError(t, database.Create(&user).Error)
+	require.NoError(t, database.Create(&db.Decision{ActorID: user.ID, RecipientID: user.ID, Liked: true}).Error)
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied, s.Name)
+		assert.False(t, s.AppliedAt.IsZero(), s.Name)
+	}
+
+	// revert everything
+	reverted, err := m.Down(ctx, len(statuses))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(statuses))
+	assert.False(t, migrator.HasTable("decisions"))
+	assert.ErrorIs(t, m.EnsureCurrent(ctx), migrate.ErrSchemaBehind)
+}
+
+func TestConcurrentUpAppliesOnce(t *testing.T) {
+	ctx := context.Background()
+	database := openSQLite(t)
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		total int
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m, err := migrate.New(database)
+			require.NoError(t, err)
+			applied, err := m.Up(ctx)
+			assert.NoError(t, err)
+			mu.Lock()
+			total += len(applied)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	m, err := migrate.New(database)
+	require.NoError(t, err)
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(statuses), total)
+}
+
+func TestLoadValidation(t *testing.T) {
+	_, err := migrate.Load(fstest.MapFS{
+		"sqlite/0001_init.up.sql": {Data: []byte("CREATE TABLE a (id INTEGER);")},
+	}, "sqlite")
+	assert.ErrorContains(t, err, "needs both up and down")
+
+	_, err = migrate.Load(fstest.MapFS{
+		"sqlite/init.sql": {Data: []byte("")},
+	}, "sqlite")
+	assert.ErrorContains(t, err, "invalid migration file name")
+
+	list, err := migrate.Load(fstest.MapFS{
+		"sqlite/0002_b.up.sql":   {Data: []byte("x")},
+		"sqlite/0002_b.down.sql": {Data: []byte("x")},
+		"sqlite/0001_a.up.sql":   {Data: []byte("x")},
+		"sqlite/0001_a.down.sql": {Data: []byte("x")},
+	}, "sqlite")
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, uint64(1), list[0].Version)
+	assert.Equal(t, "b", list[1].Name)
+}
+
+func TestEmbeddedMigrationsCoverAllDialects(t *testing.T) {
+	shard, err := fs.Sub(migrations.FS, "shard")
+	require.NoError(t, err)
+
+	for name, fsys := range map[string]fs.FS{"primary": migrations.FS, "shard": shard} {
+		var versions [][]uint64
+		for _, dialect := range migrate.Dialects {
+			list, err := migrate.Load(fsys, dialect)
+			require.NoError(t, err, dialect)
+			var vs []uint64
+			for _, mig := range list {
+				vs = append(vs, mig.Version)
+			}
+			versions = append(versions, vs)
+		}
+		for i := 1; i < len(versions); i++ {
+			assert.Equal(t, versions[0], versions[i], "%s: every dialect must have the same migration versions", name)
+		}
+	}
+}
+
+func TestShardMigrations(t *testing.T) {
+	ctx := context.Background()
+	database := openSQLite(t)
+
+	m, err := migrate.NewShard(database)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	require.NoError(t, m.EnsureCurrent(ctx))
+
+	// shards only hold decisions, without foreign keys to users
+	assert.True(t, database.Migrator().HasTable("decisions"))
+	assert.False(t, database.Migrator().HasTable("users"))
+	require.NoError(t, database.Create(&db.Decision{ActorID: 1, RecipientID: 2, Liked: true}).Error)
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "mysql"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mysql", "0007_old.up.sql"), nil, 0o644))
+
+	files, err := migrate.Create(dir, "Add user-stats")
+	require.NoError(t, err)
+	assert.Len(t, files, 2*len(migrate.Dialects))
+	assert.FileExists(t, filepath.Join(dir, "sqlite", "0008_add_user_stats.up.sql"))
+	assert.FileExists(t, filepath.Join(dir, "postgres", "0008_add_user_stats.down.sql"))
+}
@@ -0,0 +1,17 @@
+// Package migrations holds the versioned SQL schema migrations.
+//
+// Layout: one directory per dialect (mysql, postgres, sqlite), each with
+// files named <version>_<name>.up.sql and <version>_<name>.down.sql.
+// Every version must exist for every dialect. New files are scaffolded
+// with `go run ./cmd/migrate create <name>`.
+//
+// shard/ has the same layout and holds the schema of the decision shards
+// (see DB_SHARD_DSNS); it is versioned independently of the primary.
+package migrations
+
+import "embed"
+
+// FS contains all migration files, embedded into the binaries.
+//
+//go:embed mysql/*.sql postgres/*.sql sqlite/*.sql shard/*/*.sql
+var FS embed.FS
@@ -0,0 +1,29 @@
+-- Baseline schema. IF NOT EXISTS lets databases created by the old
+-- AutoMigrate startup adopt this migration without changes.
+CREATE TABLE IF NOT EXISTS users (
+    id            BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    username      VARCHAR(64)  NOT NULL,
+    email         VARCHAR(128) NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    active        TINYINT(1)   DEFAULT 1,
+    last_login_at DATETIME(3)  NULL,
+    gender        VARCHAR(16)  NOT NULL,
+    created_at    DATETIME(3)  NULL,
+    updated_at    DATETIME(3)  NULL,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_users_username (username),
+    UNIQUE INDEX idx_users_email (email)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS decisions (
+    actor_id     BIGINT UNSIGNED NOT NULL,
+    recipient_id BIGINT UNSIGNED NOT NULL,
+    liked        TINYINT(1)  NOT NULL,
+    created_at   DATETIME(3) NULL,
+    updated_at   DATETIME(3) NULL,
+    PRIMARY KEY (actor_id, recipient_id),
+    INDEX idx_recipient_liked_updated_actor (recipient_id, liked, updated_at DESC),
+    INDEX idx_actor_recipient_liked (actor_id, recipient_id, liked),
+    CONSTRAINT fk_decisions_actor FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_decisions_recipient FOREIGN KEY (recipient_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,9 @@
+-- Per-user counters maintained alongside every decision (see
+-- repository.DecisionRepository.CreateOrUpdateDecision).
+CREATE TABLE IF NOT EXISTS user_stats (
+    user_id       BIGINT UNSIGNED NOT NULL PRIMARY KEY,
+    liked_you     BIGINT NOT NULL DEFAULT 0,
+    new_liked_you BIGINT NOT NULL DEFAULT 0,
+    matches       BIGINT NOT NULL DEFAULT 0,
+    updated_at    DATETIME(3) NULL
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
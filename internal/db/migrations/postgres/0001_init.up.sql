@@ -0,0 +1,28 @@
+-- Baseline schema. IF NOT EXISTS lets databases created by the old
+-- AutoMigrate startup adopt this migration without changes.
+CREATE TABLE IF NOT EXISTS users (
+    id            BIGSERIAL PRIMARY KEY,
+    username      VARCHAR(64)  NOT NULL,
+    email         VARCHAR(128) NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    active        BOOLEAN      DEFAULT TRUE,
+    last_login_at TIMESTAMPTZ,
+    gender        VARCHAR(16)  NOT NULL,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS decisions (
+    actor_id     BIGINT  NOT NULL,
+    recipient_id BIGINT  NOT NULL,
+    liked        BOOLEAN NOT NULL,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ,
+    PRIMARY KEY (actor_id, recipient_id),
+    CONSTRAINT fk_decisions_actor FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_decisions_recipient FOREIGN KEY (recipient_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_recipient_liked_updated_actor ON decisions (recipient_id, liked, updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_actor_recipient_liked ON decisions (actor_id, recipient_id, liked);
@@ -0,0 +1,12 @@
+-- Decisions shard. Users live on the primary database, so shards have
+-- no foreign keys to users.
+CREATE TABLE IF NOT EXISTS decisions (
+    actor_id     BIGINT UNSIGNED NOT NULL,
+    recipient_id BIGINT UNSIGNED NOT NULL,
+    liked        TINYINT(1)  NOT NULL,
+    created_at   DATETIME(3) NULL,
+    updated_at   DATETIME(3) NULL,
+    PRIMARY KEY (actor_id, recipient_id),
+    INDEX idx_recipient_liked_updated_actor (recipient_id, liked, updated_at DESC),
+    INDEX idx_actor_recipient_liked (actor_id, recipient_id, liked)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
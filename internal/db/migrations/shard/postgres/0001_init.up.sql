@@ -0,0 +1,12 @@
+-- Decisions shard. Users live on the primary database, so shards have
+-- no foreign keys to users.
+CREATE TABLE IF NOT EXISTS decisions (
+    actor_id     BIGINT  NOT NULL,
+    recipient_id BIGINT  NOT NULL,
+    liked        BOOLEAN NOT NULL,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ,
+    PRIMARY KEY (actor_id, recipient_id)
+);
+CREATE INDEX IF NOT EXISTS idx_recipient_liked_updated_actor ON decisions (recipient_id, liked, updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_actor_recipient_liked ON decisions (actor_id, recipient_id, liked);
@@ -0,0 +1,9 @@
+-- Per-user counters maintained alongside every decision (see
+-- repository.DecisionRepository.CreateOrUpdateDecision).
+CREATE TABLE IF NOT EXISTS user_stats (
+    user_id       BIGINT PRIMARY KEY,
+    liked_you     BIGINT NOT NULL DEFAULT 0,
+    new_liked_you BIGINT NOT NULL DEFAULT 0,
+    matches       BIGINT NOT NULL DEFAULT 0,
+    updated_at    TIMESTAMPTZ
+);
@@ -0,0 +1,9 @@
+-- Per-user counters maintained alongside every decision (see
+-- repository.DecisionRepository.CreateOrUpdateDecision).
+CREATE TABLE IF NOT EXISTS user_stats (
+    user_id       INTEGER PRIMARY KEY,
+    liked_you     INTEGER NOT NULL DEFAULT 0,
+    new_liked_you INTEGER NOT NULL DEFAULT 0,
+    matches       INTEGER NOT NULL DEFAULT 0,
+    updated_at    DATETIME
+);
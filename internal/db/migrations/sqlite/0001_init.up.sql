@@ -0,0 +1,28 @@
+-- Baseline schema. IF NOT EXISTS lets databases created by the old
+-- AutoMigrate startup adopt this migration without changes.
+CREATE TABLE IF NOT EXISTS users (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    username      TEXT     NOT NULL,
+    email         TEXT     NOT NULL,
+    password_hash TEXT     NOT NULL,
+    active        NUMERIC  DEFAULT true,
+    last_login_at DATETIME,
+    gender        TEXT     NOT NULL,
+    created_at    DATETIME,
+    updated_at    DATETIME
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS decisions (
+    actor_id     INTEGER NOT NULL,
+    recipient_id INTEGER NOT NULL,
+    liked        NUMERIC NOT NULL,
+    created_at   DATETIME,
+    updated_at   DATETIME,
+    PRIMARY KEY (actor_id, recipient_id),
+    CONSTRAINT fk_decisions_actor FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_decisions_recipient FOREIGN KEY (recipient_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_recipient_liked_updated_actor ON decisions (recipient_id, liked, updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_actor_recipient_liked ON decisions (actor_id, recipient_id, liked);
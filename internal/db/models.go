@@ -41,6 +41,10 @@ type User struct {
 //   - idx_actor_recipient_liked(actor_id, recipient_id, liked)
 //     Optimizes O(1) lookup for mutual like checks.
 //
+// Portability:
+//   - No dialect-specific column types; Liked maps to tinyint(1) on MySQL,
+//     boolean on PostgreSQL and integer on SQLite.
+//
 // Fields:
 //   - ActorID: The user making the decision.
 //   - RecipientID: The user being liked/passed.
@@ -50,7 +54,7 @@ type User struct {
 type Decision struct {
 	ActorID     uint64    `gorm:"primaryKey;index:idx_actor_recipient_liked,priority:1"`
 	RecipientID uint64    `gorm:"primaryKey;index:idx_recipient_liked_updated_actor,priority:1;index:idx_actor_recipient_liked,priority:2"`
-	Liked       bool      `gorm:"not null;type:tinyint(1);index:idx_recipient_liked_updated_actor,priority:2;index:idx_actor_recipient_liked,priority:3"`
+	Liked       bool      `gorm:"not null;index:idx_recipient_liked_updated_actor,priority:2;index:idx_actor_recipient_liked,priority:3"`
 	CreatedAt   time.Time `gorm:"autoCreateTime"`
 	UpdatedAt   time.Time `gorm:"autoUpdateTime;index:idx_recipient_liked_updated_actor,priority:3,sort:desc"`
 
@@ -58,3 +62,24 @@ type Decision struct {
 	Actor     User `gorm:"foreignKey:ActorID;constraint:OnDelete:CASCADE"`
 	Recipient User `gorm:"foreignKey:RecipientID;constraint:OnDelete:CASCADE"`
 }
+
+// UserStats holds denormalized counters for one user, kept in step with
+// decisions so counts do not need a COUNT over the decisions table.
+//
+// Counters (for user U):
+//   - LikedYou: users who liked U and were not passed by U (CountLikedYou).
+//   - NewLikedYou: users who liked U and U has not decided on yet.
+//   - Matches: users U liked who also liked U.
+//
+// Lives next to U's decisions (U's shard when sharded). Rows are created on
+// the first decision involving U; users without a row fall back to COUNT.
+type UserStats struct {
+	UserID      uint64    `gorm:"primaryKey;autoIncrement:false"`
+	LikedYou    int64     `gorm:"not null;default:0"`
+	NewLikedYou int64     `gorm:"not null;default:0"`
+	Matches     int64     `gorm:"not null;default:0"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+// TableName pins the table name; gorm would not pluralize "stats" reliably.
+func (UserStats) TableName() string { return "user_stats" }
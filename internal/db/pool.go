@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/oggyb/muzz-exercise/internal/config"
+	"github.com/oggyb/muzz-exercise/internal/metrics"
+)
+
+// configurePool applies cfg.DB's pool settings; zero values keep the
+// database/sql defaults (unlimited open, 2 idle, no lifetime).
+func configurePool(sqlDB *sql.DB, cfg *config.Config) {
+	if cfg.DB.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	}
+	if cfg.DB.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+	}
+	if cfg.DB.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.DB.ConnMaxLifetime)
+	}
+}
+
+// WatchPoolStats logs the connection pools of every open database each
+// interval until ctx is done (see metrics.DBPoolStats).
+//
+// Behavior:
+//   - one "db pool stats" line per database at debug level
+//   - at warn level instead ("db pool exhausted") when queries had to wait
+//     for a free connection since the previous tick: raise DB_MAX_OPEN_CONNS
+//     or look for slow queries holding connections
+//
+// Example:
+//
+//	go db.WatchPoolStats(ctx, logger.L(), time.Minute)
+func WatchPoolStats(ctx context.Context, log *slog.Logger, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	prev := map[string]sql.DBStats{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		stats := metrics.DBPoolStats()
+		names := make([]string, 0, len(stats))
+		for name := range stats {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		for _, name := range names {
+			s, last := stats[name], prev[name]
+			level, msg := slog.LevelDebug, "db pool stats"
+			waits := s.WaitCount - last.WaitCount
+			if waits > 0 {
+				level, msg = slog.LevelWarn, "db pool exhausted"
+			}
+			log.Log(ctx, level, msg,
+				"db", name,
+				"open", s.OpenConnections,
+				"in_use", s.InUse,
+				"idle", s.Idle,
+				"max_open", s.MaxOpenConnections,
+				"waits", waits,
+				"wait_duration", s.WaitDuration-last.WaitDuration,
+				"closed_max_lifetime", s.MaxLifetimeClosed-last.MaxLifetimeClosed,
+			)
+		}
+		prev = stats
+	}
+}
@@ -14,11 +14,12 @@ import (
 // SeedTestData resets the database and populates it with demo users and decisions.
 //
 // Behavior:
-//  1. Clears existing data in `users` and `decisions` tables.
+//  1. Clears existing data in `users`, `decisions` and `user_stats` tables.
+//     Seeded users have no stats rows; they are created on their next decision.
 //  2. Creates 20 users (10 male, 10 female) with hashed passwords.
 //  3. Generates ~200+ decisions with ~70% likes, and every 3rd ensures a mutual like.
 //
-// Compatible with both MySQL and SQLite (AUTO_INCREMENT reset skipped for SQLite).
+// Compatible with MySQL, PostgreSQL and SQLite (sequence reset is dialect-specific).
 func SeedTestData(db *gorm.DB) error {
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 
@@ -26,15 +27,20 @@ func SeedTestData(db *gorm.DB) error {
 	if err := db.Exec("DELETE FROM decisions").Error; err != nil {
 		return fmt.Errorf("failed to clear decisions: %w", err)
 	}
+	if err := db.Exec("DELETE FROM user_stats").Error; err != nil {
+		return fmt.Errorf("failed to clear user stats: %w", err)
+	}
 	if err := db.Exec("DELETE FROM users").Error; err != nil {
 		return fmt.Errorf("failed to clear users: %w", err)
 	}
 
-	// Reset auto-increment sequences (mainly for MySQL / SQLite)
+	// Reset auto-increment sequences (MySQL / PostgreSQL / SQLite)
 	switch db.Dialector.Name() {
 	case "mysql":
 		db.Exec("ALTER TABLE users AUTO_INCREMENT = 1")
 		db.Exec("ALTER TABLE decisions AUTO_INCREMENT = 1")
+	case "postgres":
+		db.Exec("ALTER SEQUENCE users_id_seq RESTART WITH 1")
 	case "sqlite":
 		db.Exec("DELETE FROM sqlite_sequence WHERE name = 'users'")
 		db.Exec("DELETE FROM sqlite_sequence WHERE name = 'decisions'")
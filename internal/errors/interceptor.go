@@ -0,0 +1,55 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/oggyb/muzz-exercise/internal/logger"
+)
+
+// UnaryServerInterceptor keeps internal errors on the server.
+//
+// Behavior:
+//   - A handler error that is not a status error goes through Map, so it
+//     reaches the client as Internal instead of its raw text.
+//   - The cause of an *Error is logged with the request logger: at error
+//     level for Internal and Unknown, warn otherwise.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			err = mapAndLog(ctx, err)
+		}
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streams.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, ss)
+		if err != nil {
+			err = mapAndLog(ss.Context(), err)
+		}
+		return err
+	}
+}
+
+func mapAndLog(ctx context.Context, err error) error {
+	err = Map(err)
+	var e *Error
+	if !errors.As(err, &e) || e.cause == nil {
+		return err
+	}
+	lvl := slog.LevelWarn
+	if code := e.status.Code(); code == codes.Internal || code == codes.Unknown {
+		lvl = slog.LevelError
+	}
+	logger.FromContext(ctx).Log(ctx, lvl, "request failed",
+		"code", e.status.Code().String(), "reason", Reason(err), "err", e.cause)
+	return err
+}
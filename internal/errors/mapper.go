@@ -4,42 +4,208 @@ package errors
 import (
 	"context"
 	"errors"
+	"time"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"gorm.io/gorm"
+
+	"github.com/oggyb/muzz-exercise/internal/utils/pagination"
+)
+
+// Domain is the ErrorInfo domain of every error this server returns.
+const Domain = "muzz-explore"
+
+// Stable ErrorInfo reasons. Clients may switch on them; never rename one.
+const (
+	ReasonInvalidArgument    = "INVALID_ARGUMENT"
+	ReasonInvalidPageToken   = "INVALID_PAGE_TOKEN"
+	ReasonNotFound           = "NOT_FOUND"
+	ReasonAlreadyExists      = "ALREADY_EXISTS"
+	ReasonUsernameTaken      = "USERNAME_TAKEN"
+	ReasonEmailTaken         = "EMAIL_TAKEN"
+	ReasonMissingReference   = "MISSING_REFERENCE"
+	ReasonConflict           = "CONFLICT"
+	ReasonTokenMissing       = "TOKEN_MISSING"
+	ReasonTokenInvalid       = "TOKEN_INVALID"
+	ReasonInvalidCredentials = "INVALID_CREDENTIALS"
+	ReasonRefreshInvalid     = "REFRESH_TOKEN_INVALID"
+	ReasonNotOwner           = "NOT_OWNER"
+	ReasonAccountDisabled    = "ACCOUNT_DISABLED"
+	ReasonUnavailable        = "DEPENDENCY_UNAVAILABLE"
+	ReasonTimeout            = "TIMEOUT"
+	ReasonCanceled           = "CANCELED"
+	ReasonInternal           = "INTERNAL"
 )
 
+// RetryDelay is the RetryInfo delay suggested with Unavailable and Aborted.
+const RetryDelay = time.Second
+
+// Error is a gRPC status error that keeps the server-side cause it was
+// mapped from. Clients only receive the status; the cause is for logs
+// (see UnaryServerInterceptor) and errors.Is/As.
+type Error struct {
+	status *status.Status
+	cause  error
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.status.Message() + ": " + e.cause.Error()
+	}
+	return e.status.Message()
+}
+
+// GRPCStatus is what grpc sends to the client.
+func (e *Error) GRPCStatus() *status.Status { return e.status }
+
+// Unwrap returns the cause.
+func (e *Error) Unwrap() error { return e.cause }
+
+// FieldViolation names a request field and what is wrong with it.
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
 // Map converts repo/infra errors into gRPC-friendly status errors.
 // Keeps service layer clean by centralizing error mapping.
+//
+// Behavior:
+//   - Status errors (e.g. from the constructors below) pass through.
+//   - Record not found → NotFound; invalid pagination token →
+//     InvalidArgument on pagination_token.
+//   - Duplicate key → AlreadyExists; foreign key violation →
+//     FailedPrecondition; deadlock or serialization failure → Aborted
+//     with RetryInfo (MySQL, PostgreSQL and SQLite).
+//   - Anything else → Internal with a generic message: driver errors may
+//     quote SQL and values, so the cause stays on the server.
+//
+// Example:
+//
+//	if err := repo.HasLiked(ctx, a, b); err != nil { return nil, svcErr.Map(err) }
 func Map(err error) error {
 	if err == nil {
 		return nil
 	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
 
 	switch {
 	case errors.Is(err, gorm.ErrRecordNotFound):
-		return status.Error(codes.NotFound, "record not found")
+		return wrap(err, codes.NotFound, ReasonNotFound, "record not found")
+
+	case errors.Is(err, pagination.ErrInvalidToken):
+		// tampered, expired or reused on another list: the client's fault
+		return wrap(err, codes.InvalidArgument, ReasonInvalidPageToken, err.Error(),
+			badRequest(FieldViolation{"pagination_token", err.Error()}))
 
 	case errors.Is(err, context.DeadlineExceeded):
-		return status.Error(codes.DeadlineExceeded, "request timed out")
+		return wrap(err, codes.DeadlineExceeded, ReasonTimeout, "request timed out")
 
 	case errors.Is(err, context.Canceled):
-		return status.Error(codes.Canceled, "request was canceled")
+		return wrap(err, codes.Canceled, ReasonCanceled, "request was canceled")
+	}
 
-	default:
-		// fallback → bubble up error message for debugging
-		return status.Error(codes.Internal, err.Error())
+	switch classifySQL(err) {
+	case sqlDuplicate:
+		return wrap(err, codes.AlreadyExists, ReasonAlreadyExists, "record already exists")
+	case sqlForeignKey:
+		return wrap(err, codes.FailedPrecondition, ReasonMissingReference, "a referenced record does not exist")
+	case sqlConflict:
+		return wrap(err, codes.Aborted, ReasonConflict, "conflicting concurrent update, retry", retryInfo())
 	}
+
+	return wrap(err, codes.Internal, ReasonInternal, "internal error")
 }
 
 // InvalidArgument creates a gRPC InvalidArgument error.
-// Use this in service layer for bad input validation.
-func InvalidArgument(msg string) error {
-	return status.Error(codes.InvalidArgument, msg)
+// Use this in service layer for bad input validation; violations, when
+// given, are attached as BadRequest details.
+func InvalidArgument(msg string, violations ...FieldViolation) error {
+	var details []protoadapt.MessageV1
+	if len(violations) > 0 {
+		details = append(details, badRequest(violations...))
+	}
+	return wrap(nil, codes.InvalidArgument, ReasonInvalidArgument, msg, details...)
+}
+
+// InvalidField creates an InvalidArgument error about one field, with the
+// message "<field> <problem>".
+//
+// Example:
+//
+//	svcErr.InvalidField("recipient_user_id", "must be a valid uint64")
+func InvalidField(field, problem string) error {
+	msg := field + " " + problem
+	return InvalidArgument(msg, FieldViolation{field, msg})
 }
 
 // AlreadyExists creates a gRPC AlreadyExists error.
-func AlreadyExists(msg string) error {
-	return status.Error(codes.AlreadyExists, msg)
+func AlreadyExists(reason, msg string) error {
+	return wrap(nil, codes.AlreadyExists, reason, msg)
+}
+
+// Unauthenticated creates a gRPC Unauthenticated error (missing or bad credentials).
+func Unauthenticated(reason, msg string) error {
+	return wrap(nil, codes.Unauthenticated, reason, msg)
+}
+
+// PermissionDenied creates a gRPC PermissionDenied error (valid caller, wrong resource).
+func PermissionDenied(reason, msg string) error {
+	return wrap(nil, codes.PermissionDenied, reason, msg)
+}
+
+// Unavailable creates a gRPC Unavailable error (a dependency is down; retry
+// later), suggesting RetryDelay. cause is logged, not sent.
+func Unavailable(cause error, msg string) error {
+	return wrap(cause, codes.Unavailable, ReasonUnavailable, msg, retryInfo())
+}
+
+// Internal creates a gRPC Internal error (a bug or broken invariant on our side).
+func Internal(msg string) error {
+	return wrap(nil, codes.Internal, ReasonInternal, msg)
+}
+
+// wrap builds an *Error with an ErrorInfo detail followed by details.
+func wrap(cause error, code codes.Code, reason, msg string, details ...protoadapt.MessageV1) error {
+	st := status.New(code, msg)
+	all := append([]protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: reason, Domain: Domain}}, details...)
+	if withDetails, err := st.WithDetails(all...); err == nil {
+		st = withDetails
+	}
+	return &Error{status: st, cause: cause}
+}
+
+func badRequest(violations ...FieldViolation) *errdetails.BadRequest {
+	br := &errdetails.BadRequest{}
+	for _, v := range violations {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+	return br
+}
+
+func retryInfo() *errdetails.RetryInfo {
+	return &errdetails.RetryInfo{RetryDelay: durationpb.New(RetryDelay)}
+}
+
+// Reason returns the ErrorInfo reason of a status error, or "".
+func Reason(err error) string {
+	st, ok := status.FromError(err)
+	if !ok {
+		return ""
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info.GetReason()
+		}
+	}
+	return ""
 }
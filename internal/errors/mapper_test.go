@@ -0,0 +1,124 @@
+package errors_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	svcErr "github.com/oggyb/muzz-exercise/internal/errors"
+	"github.com/oggyb/muzz-exercise/internal/logger"
+	"github.com/oggyb/muzz-exercise/internal/utils/pagination"
+)
+
+func details[T any](t *testing.T, err error) *T {
+	t.Helper()
+	for _, d := range status.Convert(err).Details() {
+		if v, ok := d.(*T); ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func TestMapDatabaseErrors(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:?_foreign_keys=1"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gdb.Exec("CREATE TABLE parents (id INTEGER PRIMARY KEY)").Error)
+	require.NoError(t, gdb.Exec("CREATE TABLE children (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parents(id))").Error)
+	require.NoError(t, gdb.Exec("INSERT INTO parents (id) VALUES (1)").Error)
+
+	cases := map[string]struct {
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		"mysql duplicate":    {&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@x.io' for key 'email'"}, codes.AlreadyExists, svcErr.ReasonAlreadyExists},
+		"mysql foreign key":  {&mysql.MySQLError{Number: 1452}, codes.FailedPrecondition, svcErr.ReasonMissingReference},
+		"mysql deadlock":     {fmt.Errorf("upsert: %w", &mysql.MySQLError{Number: 1213}), codes.Aborted, svcErr.ReasonConflict},
+		"postgres duplicate": {&pgconn.PgError{Code: "23505"}, codes.AlreadyExists, svcErr.ReasonAlreadyExists},
+		"postgres serialize": {&pgconn.PgError{Code: "40001"}, codes.Aborted, svcErr.ReasonConflict},
+		"sqlite duplicate":   {gdb.Exec("INSERT INTO parents (id) VALUES (1)").Error, codes.AlreadyExists, svcErr.ReasonAlreadyExists},
+		"sqlite foreign key": {gdb.Exec("INSERT INTO children (id, parent_id) VALUES (1, 2)").Error, codes.FailedPrecondition, svcErr.ReasonMissingReference},
+		"not found":          {gorm.ErrRecordNotFound, codes.NotFound, svcErr.ReasonNotFound},
+		"unknown":            {errors.New("Error 1146: Table 'muzz.decisions' doesn't exist"), codes.Internal, svcErr.ReasonInternal},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			require.Error(t, c.err)
+			mapped := svcErr.Map(c.err)
+			st := status.Convert(mapped)
+			assert.Equal(t, c.code, st.Code())
+			assert.Equal(t, c.reason, svcErr.Reason(mapped))
+			assert.ErrorIs(t, mapped, c.err, "cause kept for the server")
+
+			// no driver text reaches the client
+			assert.NotContains(t, st.Message(), "muzz.decisions")
+			assert.NotContains(t, st.Message(), "a@x.io")
+			if c.code == codes.Aborted {
+				require.NotNil(t, details[errdetails.RetryInfo](t, mapped))
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: 1213}
+
+	assert.True(t, svcErr.Retryable(fmt.Errorf("upsert: %w", deadlock)))
+	assert.True(t, svcErr.Retryable(driver.ErrBadConn))
+	assert.True(t, svcErr.Retryable(svcErr.CommitFailed(deadlock)), "rolled back at commit")
+	assert.False(t, svcErr.Retryable(svcErr.CommitFailed(driver.ErrBadConn)), "commit outcome unknown")
+	assert.False(t, svcErr.Retryable(svcErr.CommitFailed(mysql.ErrInvalidConn)), "commit outcome unknown")
+	assert.False(t, svcErr.Retryable(errors.New("syntax error")))
+}
+
+func TestInvalidArgumentDetails(t *testing.T) {
+	err := svcErr.InvalidField("recipient_user_id", "must be a valid uint64")
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "recipient_user_id must be a valid uint64", st.Message())
+	br := details[errdetails.BadRequest](t, err)
+	require.NotNil(t, br)
+	require.Len(t, br.FieldViolations, 1)
+	assert.Equal(t, "recipient_user_id", br.FieldViolations[0].Field)
+
+	info := details[errdetails.ErrorInfo](t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, svcErr.Domain, info.Domain)
+
+	br = details[errdetails.BadRequest](t, svcErr.Map(fmt.Errorf("decode: %w", pagination.ErrInvalidToken)))
+	require.NotNil(t, br)
+	assert.Equal(t, "pagination_token", br.FieldViolations[0].Field)
+}
+
+// TestInterceptorHidesCauses checks that a raw handler error reaches the
+// client as a generic Internal while its text is logged on the server.
+func TestInterceptorHidesCauses(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := logger.NewContext(context.Background(), slog.New(slog.NewTextHandler(&buf, nil)))
+	handler := func(context.Context, any) (any, error) {
+		return nil, errors.New("dial tcp 10.0.0.5:3306: connection refused")
+	}
+	_, err := svcErr.UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/x.Y/Z"}, handler)
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.Internal, st.Code())
+	assert.Equal(t, "internal error", st.Message())
+	assert.Contains(t, buf.String(), "connection refused")
+	assert.Contains(t, buf.String(), "level=ERROR")
+}
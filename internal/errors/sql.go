@@ -0,0 +1,109 @@
+package errors
+
+import (
+	"database/sql/driver"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+type sqlKind int
+
+const (
+	sqlOther sqlKind = iota
+	sqlDuplicate
+	sqlForeignKey
+	sqlConflict // deadlock, lock timeout or serialization failure: retryable
+)
+
+// classifySQL recognizes the driver errors Map turns into specific codes.
+func classifySQL(err error) sqlKind {
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return sqlDuplicate
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return sqlForeignKey
+	}
+
+	var my *mysql.MySQLError
+	if errors.As(err, &my) {
+		switch my.Number {
+		case 1062: // ER_DUP_ENTRY
+			return sqlDuplicate
+		case 1216, 1217, 1451, 1452: // ER_NO_REFERENCED_ROW(_2), ER_ROW_IS_REFERENCED(_2)
+			return sqlForeignKey
+		case 1205, 1213: // ER_LOCK_WAIT_TIMEOUT, ER_LOCK_DEADLOCK
+			return sqlConflict
+		}
+	}
+
+	var pg *pgconn.PgError
+	if errors.As(err, &pg) {
+		switch pg.Code {
+		case "23505": // unique_violation
+			return sqlDuplicate
+		case "23503": // foreign_key_violation
+			return sqlForeignKey
+		case "40001", "40P01": // serialization_failure, deadlock_detected
+			return sqlConflict
+		}
+	}
+
+	var lite sqlite3.Error
+	if errors.As(err, &lite) {
+		switch {
+		case lite.ExtendedCode == sqlite3.ErrConstraintUnique, lite.ExtendedCode == sqlite3.ErrConstraintPrimaryKey:
+			return sqlDuplicate
+		case lite.ExtendedCode == sqlite3.ErrConstraintForeignKey:
+			return sqlForeignKey
+		case lite.Code == sqlite3.ErrBusy, lite.Code == sqlite3.ErrLocked:
+			return sqlConflict
+		}
+	}
+	return sqlOther
+}
+
+// Retryable reports whether err is transient: a deadlock, lock wait
+// timeout, serialization failure or busy database, after which the
+// database has rolled the transaction back, or a connection lost before
+// the transaction finished. Running the whole operation again may succeed.
+//
+// A connection lost during COMMIT (marked with CommitFailed) is not
+// retryable: the transaction may have committed, and running it again
+// would apply it twice.
+//
+// Example:
+//
+//	if svcErr.Retryable(err) { /* back off and run the transaction again */ }
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		var commit *commitError
+		return !errors.As(err, &commit)
+	}
+	return classifySQL(err) == sqlConflict
+}
+
+// CommitFailed marks err as returned by COMMIT, so Retryable can tell an
+// unknown outcome from a rollback. The message and the wrapped error are
+// unchanged.
+//
+// Example:
+//
+//	if err := tx.Commit().Error; err != nil { return svcErr.CommitFailed(err) }
+func CommitFailed(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &commitError{err: err}
+}
+
+type commitError struct{ err error }
+
+func (e *commitError) Error() string { return e.err.Error() }
+func (e *commitError) Unwrap() error { return e.err }
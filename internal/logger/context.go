@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type loggerKey struct{}
+
+type requestIDKey struct{}
+
+// NewContext returns a copy of ctx carrying l, to be found by FromContext.
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger attached to ctx (e.g. the request logger
+// set by the gRPC interceptor), or the global logger when there is none.
+//
+// Example:
+//
+//	logger.FromContext(ctx).InfoContext(ctx, "user registered", "user_id", id)
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return L()
+}
+
+// WithContext adds attributes to the logger in ctx and returns the new context.
+func WithContext(ctx context.Context, args ...any) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the request ctx belongs to, if any.
+func RequestID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
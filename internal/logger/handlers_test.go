@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewRedactHandler(slog.NewTextHandler(&buf, nil), RedactOptions{
+		Redact: []string{"password"},
+		Hash:   []string{"Email"},
+	})
+	log := slog.New(h).With("email", "alice@example.com")
+	log.Info("login", slog.Group("req", "password", "hunter22"), "user", "alice")
+
+	out := buf.String()
+	for _, secret := range []string{"alice@example.com", "hunter22"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("%q leaked: %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "email="+hashValue("alice@example.com")) {
+		t.Errorf("expected hashed email, got: %s", out)
+	}
+	if !strings.Contains(out, "req.password="+Redacted) {
+		t.Errorf("expected redacted password in group, got: %s", out)
+	}
+	if !strings.Contains(out, "user=alice") {
+		t.Errorf("expected other fields untouched, got: %s", out)
+	}
+}
+
+func TestSampleHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewSampleHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		SampleOptions{Initial: 2, Thereafter: 3, Tick: time.Second}).(*sampleHandler)
+	now := time.Unix(0, 0)
+	h.s.now = func() time.Time { return now }
+	log := slog.New(h)
+
+	for i := 0; i < 8; i++ {
+		log.Debug("swipe", "i", i)
+		log.Warn("never sampled", "i", i)
+	}
+	// 2 initial, then the 3rd and 6th after them: i=0,1,4,7
+	if got := strings.Count(buf.String(), "msg=swipe"); got != 4 {
+		t.Errorf("expected 4 sampled lines, got %d: %s", got, buf.String())
+	}
+	if got := strings.Count(buf.String(), "never sampled"); got != 8 {
+		t.Errorf("expected every warning, got %d", got)
+	}
+
+	buf.Reset()
+	now = now.Add(time.Second)
+	log.With("k", "v").Debug("swipe")
+	if !strings.Contains(buf.String(), "msg=swipe") {
+		t.Errorf("expected counts to reset after a tick, got: %s", buf.String())
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	out := captureOutput(t, func() {
+		InitFromConfig(testConfig("info", "text", "", false))
+		log := L()
+		log.Debug("hidden")
+
+		rec := httptest.NewRecorder()
+		LevelHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader("debug")))
+		if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "debug" {
+			t.Errorf("unexpected response %d %q", rec.Code, rec.Body.String())
+		}
+		log.Debug("shown")
+
+		rec = httptest.NewRecorder()
+		LevelHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/loglevel?level=loud", nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for an unknown level, got %d", rec.Code)
+		}
+	})
+
+	if strings.Contains(out, "hidden") {
+		t.Errorf("debug log should not appear before the change, got: %s", out)
+	}
+	if !strings.Contains(out, "shown") {
+		t.Errorf("debug log should appear after the change, got: %s", out)
+	}
+}
@@ -0,0 +1,120 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// level is shared by every handler Init builds, so SetLevel applies to
+// loggers created before the change too.
+var level = new(slog.LevelVar)
+
+// ParseLevel maps debug, info, warn(ing) or error to a slog level.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q (debug, info, warn or error)", s)
+	}
+}
+
+// Level returns the current minimum level.
+func Level() slog.Level { return level.Level() }
+
+// SetLevel changes the minimum level at runtime.
+//
+// Example:
+//
+//	logger.SetLevel("debug")
+func SetLevel(s string) error {
+	lvl, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+	if prev := level.Level(); prev != lvl {
+		level.Set(lvl)
+		L().Info("log level changed", "from", prev.String(), "to", lvl.String())
+	}
+	return nil
+}
+
+// WatchLevel changes the level on SIGHUP until ctx is done.
+//
+// Behavior:
+//   - With file set, the level is read from it (e.g. a mounted ConfigMap
+//     holding "debug").
+//   - Without file, SIGHUP restores fallback, the level configured at
+//     startup, undoing a change made through LevelHandler.
+//   - An unreadable file or unknown level is logged and ignored.
+func WatchLevel(ctx context.Context, file, fallback string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+		want := fallback
+		if file != "" {
+			b, err := os.ReadFile(file)
+			if err != nil {
+				L().Error("log level file unreadable, keeping level", "file", file, "err", err)
+				continue
+			}
+			want = string(b)
+		}
+		if err := SetLevel(want); err != nil {
+			L().Error("invalid log level, keeping level", "err", err)
+		}
+	}
+}
+
+// LevelHandler serves the current level on GET and changes it on PUT or
+// POST, the new level being the request body or the "level" parameter.
+//
+// Example:
+//
+//	curl -X PUT -d debug localhost:8082/loglevel
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			want := r.URL.Query().Get("level")
+			if want == "" {
+				b, err := io.ReadAll(io.LimitReader(r.Body, 64))
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				want = string(b)
+			}
+			if err := SetLevel(want); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		fmt.Fprintln(w, strings.ToLower(Level().String()))
+	})
+}
@@ -22,6 +22,13 @@ type Config struct {
 	Format     Format
 	Component  string
 	WithSource bool
+
+	// Redact and Hash name attributes kept out of the logs (see
+	// NewRedactHandler); Sample rate-limits chatty messages (see
+	// NewSampleHandler).
+	Redact []string
+	Hash   []string
+	Sample SampleOptions
 }
 
 var (
@@ -46,10 +53,21 @@ func InitFromConfig(c *config.Config) {
 		Format:     Format(c.Log.Format),
 		Component:  c.Log.Component,
 		WithSource: c.Log.Source,
+		Redact:     c.Log.RedactKeys,
+		Hash:       c.Log.HashKeys,
+		Sample: SampleOptions{
+			Initial:    c.Log.SampleInitial,
+			Thereafter: c.Log.SampleThereafter,
+			Tick:       c.Log.SampleTick,
+		},
 	})
 }
 
 // Init sets up the global logger. Safe to call multiple times.
+//
+// Records pass through sampling, then trace IDs are added and configured
+// attributes redacted before the JSON or text output. The level can be
+// changed later with SetLevel.
 func Init(c *Config) {
 	mu.Lock()
 	defer mu.Unlock()
@@ -58,9 +76,9 @@ func Init(c *Config) {
 		cfg = *c
 	}
 
-	lvl := parseLevel(cfg.Level)
+	level.Set(parseLevel(cfg.Level))
 	opts := &slog.HandlerOptions{
-		Level:     lvl,
+		Level:     level,
 		AddSource: cfg.WithSource,
 		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
 			if a.Key == slog.TimeKey && cfg.Format == FormatText {
@@ -77,6 +95,9 @@ func Init(c *Config) {
 		handler = slog.NewTextHandler(os.Stdout, opts)
 	}
 
+	handler = NewRedactHandler(handler, RedactOptions{Redact: cfg.Redact, Hash: cfg.Hash})
+	handler = NewSampleHandler(traceHandler{handler}, cfg.Sample)
+
 	base := slog.New(handler)
 	if cfg.Component != "" {
 		base = base.With("component", cfg.Component)
@@ -111,15 +132,11 @@ func Error(msg string, args ...any) { L().Error(msg, args...) }
 
 // --- helpers ---
 
-func parseLevel(s string) slog.Leveler {
-	switch strings.ToLower(strings.TrimSpace(s)) {
-	case "debug":
-		return slog.LevelDebug
-	case "warn", "warning":
-		return slog.LevelWarn
-	case "error":
-		return slog.LevelError
-	default:
+// parseLevel is ParseLevel falling back to info.
+func parseLevel(s string) slog.Level {
+	lvl, err := ParseLevel(s)
+	if err != nil {
 		return slog.LevelInfo
 	}
+	return lvl
 }
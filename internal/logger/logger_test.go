@@ -29,21 +29,19 @@ func captureOutput(t *testing.T, f func()) string {
 	return buf.String()
 }
 
+// testConfig builds an app config with only the logger section populated
+func testConfig(level, format, component string, source bool) *config.Config {
+	cfg := &config.Config{}
+	cfg.Log.Level = level
+	cfg.Log.Format = format
+	cfg.Log.Component = component
+	cfg.Log.Source = source
+	return cfg
+}
+
 func TestLogger_TextFormat(t *testing.T) {
 	out := captureOutput(t, func() {
-		InitFromConfig(config.Config{
-			Log: struct {
-				Level     string
-				Format    string
-				Component string
-				Source    bool
-			}{
-				Level:     "debug",
-				Format:    "text",
-				Component: "test",
-				Source:    false,
-			},
-		})
+		InitFromConfig(testConfig("debug", "text", "test", false))
 		Info("hello muzz", "key", "value")
 	})
 
@@ -60,19 +58,7 @@ func TestLogger_TextFormat(t *testing.T) {
 
 func TestLogger_JSONFormat(t *testing.T) {
 	out := captureOutput(t, func() {
-		InitFromConfig(config.Config{
-			Log: struct {
-				Level     string
-				Format    string
-				Component string
-				Source    bool
-			}{
-				Level:     "info",
-				Format:    "json",
-				Component: "json_test",
-				Source:    false,
-			},
-		})
+		InitFromConfig(testConfig("info", "json", "json_test", false))
 		Info("json log", "foo", "bar")
 	})
 
@@ -89,17 +75,7 @@ func TestLogger_JSONFormat(t *testing.T) {
 
 func TestLogger_LevelFilter(t *testing.T) {
 	out := captureOutput(t, func() {
-		InitFromConfig(config.Config{
-			Log: struct {
-				Level     string
-				Format    string
-				Component string
-				Source    bool
-			}{
-				Level:  "error",
-				Format: "text",
-			},
-		})
+		InitFromConfig(testConfig("error", "text", "", false))
 		Info("should not appear")
 		Error("should appear")
 	})
@@ -114,17 +90,7 @@ func TestLogger_LevelFilter(t *testing.T) {
 
 func TestLogger_WithAddsFields(t *testing.T) {
 	out := captureOutput(t, func() {
-		InitFromConfig(config.Config{
-			Log: struct {
-				Level     string
-				Format    string
-				Component string
-				Source    bool
-			}{
-				Level:  "debug",
-				Format: "text",
-			},
-		})
+		InitFromConfig(testConfig("debug", "text", "", false))
 		log := With("req_id", "123")
 		log.Info("processing request")
 	})
@@ -136,19 +102,7 @@ func TestLogger_WithAddsFields(t *testing.T) {
 
 func TestLogger_InitFromConfig(t *testing.T) {
 	out := captureOutput(t, func() {
-		InitFromConfig(config.Config{
-			Log: struct {
-				Level     string
-				Format    string
-				Component string
-				Source    bool
-			}{
-				Level:     "debug",
-				Format:    "json",
-				Component: "cfg_test",
-				Source:    true,
-			},
-		})
+		InitFromConfig(testConfig("debug", "json", "cfg_test", true))
 		Debug("cfg-based log")
 	})
 
@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+)
+
+// Redacted replaces the value of attributes listed in RedactOptions.Redact.
+const Redacted = "[REDACTED]"
+
+// RedactOptions lists attribute keys (case-insensitive, at any group
+// depth) whose values must not reach the logs.
+type RedactOptions struct {
+	// Redact keys are replaced by Redacted, e.g. password or token.
+	Redact []string
+	// Hash keys are replaced by a short SHA-256 digest, e.g. email: lines
+	// about the same address can still be correlated. The digest is not
+	// salted, so it only hides values that are hard to guess.
+	Hash []string
+}
+
+type redactHandler struct {
+	next slog.Handler
+	keys map[string]bool // key → hash (true) or redact (false)
+}
+
+// NewRedactHandler wraps next so the attributes named in opts are redacted
+// or hashed, including those added through With.
+//
+// Example:
+//
+//	h := logger.NewRedactHandler(slog.NewJSONHandler(os.Stdout, nil),
+//		logger.RedactOptions{Redact: []string{"password"}, Hash: []string{"email"}})
+func NewRedactHandler(next slog.Handler, opts RedactOptions) slog.Handler {
+	keys := map[string]bool{}
+	for _, k := range opts.Redact {
+		keys[strings.ToLower(k)] = false
+	}
+	for _, k := range opts.Hash {
+		keys[strings.ToLower(k)] = true
+	}
+	if len(keys) == 0 {
+		return next
+	}
+	return &redactHandler{next: next, keys: keys}
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, lvl slog.Level) bool {
+	return h.next.Enabled(ctx, lvl)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.redact(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.redact(a)
+	}
+	return &redactHandler{next: h.next.WithAttrs(redacted), keys: h.keys}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name), keys: h.keys}
+}
+
+func (h *redactHandler) redact(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if hash, ok := h.keys[strings.ToLower(a.Key)]; ok {
+		if hash {
+			return slog.String(a.Key, hashValue(a.Value.String()))
+		}
+		return slog.String(a.Key, Redacted)
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, g := range group {
+			redacted[i] = h.redact(g)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	}
+	return a
+}
+
+func hashValue(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
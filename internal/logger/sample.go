@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SampleOptions rate-limits repeated messages. Records at Warn and above
+// are never sampled.
+type SampleOptions struct {
+	// Initial records per message and Tick are kept; 0 turns sampling off.
+	Initial int
+	// Thereafter keeps every Nth record after Initial; 0 drops them all.
+	Thereafter int
+	// Tick is the window the counts reset after; defaults to one second.
+	Tick time.Duration
+}
+
+type sampleHandler struct {
+	next slog.Handler
+	s    *sampler
+}
+
+// sampler is shared by a handler and the children built by With, so a
+// message is counted once whatever attributes it carries.
+type sampler struct {
+	opts SampleOptions
+	now  func() time.Time
+
+	mu     sync.Mutex
+	window time.Time
+	counts map[sampleKey]int
+}
+
+type sampleKey struct {
+	level slog.Level
+	msg   string
+}
+
+// NewSampleHandler wraps next so each message (level and text) is logged
+// at most Initial times per Tick, then every Thereafter-th time.
+//
+// Example:
+//
+//	// PutDecision debug lines: 10 per second, then 1 in 100
+//	h := logger.NewSampleHandler(next, logger.SampleOptions{Initial: 10, Thereafter: 100})
+func NewSampleHandler(next slog.Handler, opts SampleOptions) slog.Handler {
+	if opts.Initial <= 0 {
+		return next
+	}
+	if opts.Tick <= 0 {
+		opts.Tick = time.Second
+	}
+	return &sampleHandler{next: next, s: &sampler{opts: opts, now: time.Now, counts: map[sampleKey]int{}}}
+}
+
+func (h *sampleHandler) Enabled(ctx context.Context, lvl slog.Level) bool {
+	return h.next.Enabled(ctx, lvl)
+}
+
+func (h *sampleHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn && !h.s.keep(sampleKey{r.Level, r.Message}) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *sampleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &sampleHandler{next: h.next.WithAttrs(attrs), s: h.s}
+}
+
+func (h *sampleHandler) WithGroup(name string) slog.Handler {
+	return &sampleHandler{next: h.next.WithGroup(name), s: h.s}
+}
+
+func (s *sampler) keep(k sampleKey) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now := s.now(); now.Sub(s.window) >= s.opts.Tick {
+		s.window = now
+		clear(s.counts)
+	}
+	s.counts[k]++
+	n := s.counts[k]
+	if n <= s.opts.Initial {
+		return true
+	}
+	return s.opts.Thereafter > 0 && (n-s.opts.Initial)%s.opts.Thereafter == 0
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"database/sql"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dbPools reports the connection pools added with TrackDBPool. Pools
+// sharing a name (e.g. the replicas of one shard) are summed.
+type dbPools struct {
+	mu    sync.Mutex
+	pools map[*sql.DB]string
+
+	maxOpen, open, inUse, idle, waits, waitSeconds *prometheus.Desc
+}
+
+var pools = &dbPools{
+	pools:       map[*sql.DB]string{},
+	maxOpen:     poolDesc("db_pool_max_open_connections", "Maximum open connections (0 = unlimited), by database."),
+	open:        poolDesc("db_pool_open_connections", "Open connections, in use or idle, by database."),
+	inUse:       poolDesc("db_pool_in_use_connections", "Connections currently in use, by database."),
+	idle:        poolDesc("db_pool_idle_connections", "Idle connections, by database."),
+	waits:       poolDesc("db_pool_waits_total", "Queries that waited for a free connection, by database."),
+	waitSeconds: poolDesc("db_pool_wait_seconds_total", "Time spent waiting for a free connection, by database."),
+}
+
+func poolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(name, help, []string{"db"}, nil)
+}
+
+// TrackDBPool adds the pool of db to the db_pool_* metrics and
+// DBPoolStats under name. UntrackDBPool removes it once closed.
+func TrackDBPool(name string, db *sql.DB) {
+	pools.mu.Lock()
+	defer pools.mu.Unlock()
+	pools.pools[db] = name
+}
+
+// UntrackDBPool removes a pool added with TrackDBPool.
+func UntrackDBPool(db *sql.DB) {
+	pools.mu.Lock()
+	defer pools.mu.Unlock()
+	delete(pools.pools, db)
+}
+
+// DBPoolStats returns the stats of the tracked pools by database name.
+func DBPoolStats() map[string]sql.DBStats {
+	pools.mu.Lock()
+	defer pools.mu.Unlock()
+	stats := make(map[string]sql.DBStats, len(pools.pools))
+	for db, name := range pools.pools {
+		s, sum := db.Stats(), stats[name]
+		sum.MaxOpenConnections += s.MaxOpenConnections
+		sum.OpenConnections += s.OpenConnections
+		sum.InUse += s.InUse
+		sum.Idle += s.Idle
+		sum.WaitCount += s.WaitCount
+		sum.WaitDuration += s.WaitDuration
+		sum.MaxIdleClosed += s.MaxIdleClosed
+		sum.MaxIdleTimeClosed += s.MaxIdleTimeClosed
+		sum.MaxLifetimeClosed += s.MaxLifetimeClosed
+		stats[name] = sum
+	}
+	return stats
+}
+
+// Describe implements prometheus.Collector.
+func (p *dbPools) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{p.maxOpen, p.open, p.inUse, p.idle, p.waits, p.waitSeconds} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (p *dbPools) Collect(ch chan<- prometheus.Metric) {
+	for name, s := range DBPoolStats() {
+		ch <- prometheus.MustNewConstMetric(p.maxOpen, prometheus.GaugeValue, float64(s.MaxOpenConnections), name)
+		ch <- prometheus.MustNewConstMetric(p.open, prometheus.GaugeValue, float64(s.OpenConnections), name)
+		ch <- prometheus.MustNewConstMetric(p.inUse, prometheus.GaugeValue, float64(s.InUse), name)
+		ch <- prometheus.MustNewConstMetric(p.idle, prometheus.GaugeValue, float64(s.Idle), name)
+		ch <- prometheus.MustNewConstMetric(p.waits, prometheus.CounterValue, float64(s.WaitCount), name)
+		ch <- prometheus.MustNewConstMetric(p.waitSeconds, prometheus.CounterValue, s.WaitDuration.Seconds(), name)
+	}
+}
@@ -0,0 +1,125 @@
+// Package metrics holds the Prometheus collectors of the server and the
+// hooks feeding them: gRPC interceptors, a gorm plugin and helpers for the
+// cache and counter code paths.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Cache lookup results for ObserveCache.
+const (
+	ResultHit   = "hit"
+	ResultMiss  = "miss"
+	ResultError = "error" // Redis down, breaker open or a bad cached value
+)
+
+// Registry holds every collector below plus the Go runtime and process ones.
+var Registry = prometheus.NewRegistry()
+
+var (
+	grpcHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "RPCs completed on the server, by method and status code.",
+	}, []string{"grpc_method", "grpc_code"})
+
+	grpcLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "RPC latency on the server, by method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"grpc_method", "grpc_code"})
+
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_requests_total",
+		Help: "Cache lookups by cache and result (hit, miss, error).",
+	}, []string{"cache", "result"})
+
+	counterUpdateFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "like_counter_update_failures_total",
+		Help: "Failed Redis like counter adjustments after PutDecision, by operation.",
+	}, []string{"op"})
+
+	redisBreakerState = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "redis_breaker_state",
+		Help: "State of the Redis circuit breaker: 0 closed, 1 open, 2 half-open.",
+	})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of gorm statements, by database, operation and table.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"db", "operation", "table"})
+
+	decisionMirrorFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "decision_mirror_failures_total",
+		Help: "Decisions saved on the recipient's shard whose mirror write to the actor's shard failed.",
+	})
+
+	dbRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_retries_total",
+		Help: "Repository operations run again after a transient database error, by operation.",
+	}, []string{"operation"})
+
+	dbRetryGiveUps = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_retry_giveups_total",
+		Help: "Transient database errors returned without another retry, by operation and reason (attempts, deadline).",
+	}, []string{"operation", "reason"})
+)
+
+func init() {
+	Registry.MustRegister(
+		grpcHandled, grpcLatency,
+		cacheRequests, counterUpdateFailures, redisBreakerState,
+		dbQueryDuration, dbRetries, dbRetryGiveUps, decisionMirrorFailures, pools,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves Registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveCache counts a lookup in the named cache.
+//
+// Example:
+//
+//	metrics.ObserveCache("like_count", metrics.ResultHit)
+func ObserveCache(cache, result string) {
+	cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// CounterUpdateFailed counts a failed like counter adjustment (op is
+// incr, decr or expire). The count in Redis is off until its TTL expires.
+func CounterUpdateFailed(op string) {
+	counterUpdateFailures.WithLabelValues(op).Inc()
+}
+
+// SetRedisBreakerState records the Redis circuit breaker state
+// (0 closed, 1 open, 2 half-open).
+func SetRedisBreakerState(state int) {
+	redisBreakerState.Set(float64(state))
+}
+
+// DecisionMirrorFailed counts a decision whose mirror write to the actor's
+// shard failed after retries. The actor's shard is stale for that pair
+// until the decision is put again.
+func DecisionMirrorFailed() {
+	decisionMirrorFailures.Inc()
+}
+
+// DBRetried counts one retry of a repository operation.
+func DBRetried(operation string) {
+	dbRetries.WithLabelValues(operation).Inc()
+}
+
+// DBRetryGaveUp counts a transient error returned to the caller because
+// the attempts ran out or the context deadline left no room for another.
+func DBRetryGaveUp(operation, reason string) {
+	dbRetryGiveUps.WithLabelValues(operation, reason).Inc()
+}
@@ -0,0 +1,77 @@
+package metrics_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/oggyb/muzz-exercise/internal/metrics"
+)
+
+// TestUnaryInterceptorCountsByMethodAndCode checks request counts and
+// latency samples are labelled with the method and status code.
+func TestUnaryInterceptorCountsByMethodAndCode(t *testing.T) {
+	intercept := metrics.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Svc/Call"}
+	ok := func(context.Context, any) (any, error) { return "ok", nil }
+	denied := func(context.Context, any) (any, error) { return nil, status.Error(codes.PermissionDenied, "no") }
+
+	for range 2 {
+		_, err := intercept(context.Background(), nil, info, ok)
+		require.NoError(t, err)
+	}
+	_, err := intercept(context.Background(), nil, info, denied)
+	require.Error(t, err)
+
+	expected := `
+# HELP grpc_server_handled_total RPCs completed on the server, by method and status code.
+# TYPE grpc_server_handled_total counter
+grpc_server_handled_total{grpc_code="OK",grpc_method="/test.Svc/Call"} 2
+grpc_server_handled_total{grpc_code="PermissionDenied",grpc_method="/test.Svc/Call"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(metrics.Registry, strings.NewReader(expected), "grpc_server_handled_total"))
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.Registry, "grpc_server_handling_seconds"))
+}
+
+// TestGormPluginObservesQueries checks statements are timed per database,
+// operation and table.
+func TestGormPluginObservesQueries(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file:metrics?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gdb.Use(metrics.NewGormPlugin("testdb")))
+
+	type widget struct{ ID uint }
+	require.NoError(t, gdb.AutoMigrate(&widget{}))
+	require.NoError(t, gdb.Create(&widget{}).Error)
+	var got []widget
+	require.NoError(t, gdb.Find(&got).Error)
+
+	families, err := metrics.Registry.Gather()
+	require.NoError(t, err)
+	seen := map[string]uint64{}
+	for _, f := range families {
+		if f.GetName() != "db_query_duration_seconds" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["db"] == "testdb" {
+				seen[labels["operation"]+" "+labels["table"]] += m.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	assert.Equal(t, uint64(1), seen["create widgets"])
+	assert.Equal(t, uint64(1), seen["query widgets"])
+}
@@ -22,11 +22,13 @@ const (
 )
 
 type ListLikedYouRequest struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	RecipientUserId string                 `protobuf:"bytes,1,opt,name=recipient_user_id,json=recipientUserId,proto3" json:"recipient_user_id,omitempty"`
-	PaginationToken *string                `protobuf:"bytes,2,opt,name=pagination_token,json=paginationToken,proto3,oneof" json:"pagination_token,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	RecipientUserId    string                 `protobuf:"bytes,1,opt,name=recipient_user_id,json=recipientUserId,proto3" json:"recipient_user_id,omitempty"`
+	PaginationToken    *string                `protobuf:"bytes,2,opt,name=pagination_token,json=paginationToken,proto3,oneof" json:"pagination_token,omitempty"`             // next_pagination_token or prev_pagination_token of an earlier page
+	SinceUnixTimestamp *uint64                `protobuf:"varint,3,opt,name=since_unix_timestamp,json=sinceUnixTimestamp,proto3,oneof" json:"since_unix_timestamp,omitempty"` // only likes after this time (unix millis, exclusive)
+	UntilUnixTimestamp *uint64                `protobuf:"varint,4,opt,name=until_unix_timestamp,json=untilUnixTimestamp,proto3,oneof" json:"until_unix_timestamp,omitempty"` // only likes up to this time (unix millis, inclusive)
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *ListLikedYouRequest) Reset() {
@@ -73,10 +75,25 @@ func (x *ListLikedYouRequest) GetPaginationToken() string {
 	return ""
 }
 
+func (x *ListLikedYouRequest) GetSinceUnixTimestamp() uint64 {
+	if x != nil && x.SinceUnixTimestamp != nil {
+		return *x.SinceUnixTimestamp
+	}
+	return 0
+}
+
+func (x *ListLikedYouRequest) GetUntilUnixTimestamp() uint64 {
+	if x != nil && x.UntilUnixTimestamp != nil {
+		return *x.UntilUnixTimestamp
+	}
+	return 0
+}
+
 type ListLikedYouResponse struct {
 	state               protoimpl.MessageState        `protogen:"open.v1"`
 	Likers              []*ListLikedYouResponse_Liker `protobuf:"bytes,1,rep,name=likers,proto3" json:"likers,omitempty"`
-	NextPaginationToken *string                       `protobuf:"bytes,2,opt,name=next_pagination_token,json=nextPaginationToken,proto3,oneof" json:"next_pagination_token,omitempty"`
+	NextPaginationToken *string                       `protobuf:"bytes,2,opt,name=next_pagination_token,json=nextPaginationToken,proto3,oneof" json:"next_pagination_token,omitempty"` // older likes
+	PrevPaginationToken *string                       `protobuf:"bytes,3,opt,name=prev_pagination_token,json=prevPaginationToken,proto3,oneof" json:"prev_pagination_token,omitempty"` // likes newer than the first one on this page
 	unknownFields       protoimpl.UnknownFields
 	sizeCache           protoimpl.SizeCache
 }
@@ -125,6 +142,13 @@ func (x *ListLikedYouResponse) GetNextPaginationToken() string {
 	return ""
 }
 
+func (x *ListLikedYouResponse) GetPrevPaginationToken() string {
+	if x != nil && x.PrevPaginationToken != nil {
+		return *x.PrevPaginationToken
+	}
+	return ""
+}
+
 type CountLikedYouRequest struct {
 	state           protoimpl.MessageState `protogen:"open.v1"`
 	RecipientUserId string                 `protobuf:"bytes,1,opt,name=recipient_user_id,json=recipientUserId,proto3" json:"recipient_user_id,omitempty"`
@@ -373,18 +397,24 @@ var File_explore_service_proto protoreflect.FileDescriptor
 
 const file_explore_service_proto_rawDesc = "" +
 	"\n" +
-	"\x15explore-service.proto\x12\aexplore\"\x86\x01\n" +
+	"\x15explore-service.proto\x12\aexplore\"\xa6\x02\n" +
 	"\x13ListLikedYouRequest\x12*\n" +
 	"\x11recipient_user_id\x18\x01 \x01(\tR\x0frecipientUserId\x12.\n" +
-	"\x10pagination_token\x18\x02 \x01(\tH\x00R\x0fpaginationToken\x88\x01\x01B\x13\n" +
-	"\x11_pagination_token\"\xf1\x01\n" +
+	"\x10pagination_token\x18\x02 \x01(\tH\x00R\x0fpaginationToken\x88\x01\x01\x125\n" +
+	"\x14since_unix_timestamp\x18\x03 \x01(\x04H\x01R\x12sinceUnixTimestamp\x88\x01\x01\x125\n" +
+	"\x14until_unix_timestamp\x18\x04 \x01(\x04H\x02R\x12untilUnixTimestamp\x88\x01\x01B\x13\n" +
+	"\x11_pagination_tokenB\x17\n" +
+	"\x15_since_unix_timestampB\x17\n" +
+	"\x15_until_unix_timestamp\"\xc4\x02\n" +
 	"\x14ListLikedYouResponse\x12;\n" +
 	"\x06likers\x18\x01 \x03(\v2#.explore.ListLikedYouResponse.LikerR\x06likers\x127\n" +
-	"\x15next_pagination_token\x18\x02 \x01(\tH\x00R\x13nextPaginationToken\x88\x01\x01\x1aI\n" +
+	"\x15next_pagination_token\x18\x02 \x01(\tH\x00R\x13nextPaginationToken\x88\x01\x01\x127\n" +
+	"\x15prev_pagination_token\x18\x03 \x01(\tH\x01R\x13prevPaginationToken\x88\x01\x01\x1aI\n" +
 	"\x05Liker\x12\x19\n" +
 	"\bactor_id\x18\x01 \x01(\tR\aactorId\x12%\n" +
 	"\x0eunix_timestamp\x18\x02 \x01(\x04R\runixTimestampB\x18\n" +
-	"\x16_next_pagination_token\"B\n" +
+	"\x16_next_pagination_tokenB\x18\n" +
+	"\x16_prev_pagination_token\"B\n" +
 	"\x14CountLikedYouRequest\x12*\n" +
 	"\x11recipient_user_id\x18\x01 \x01(\tR\x0frecipientUserId\"-\n" +
 	"\x15CountLikedYouResponse\x12\x14\n" +
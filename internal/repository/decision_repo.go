@@ -2,31 +2,90 @@ package repository
 
 import (
 	"context"
-	"errors"
 	"github.com/oggyb/muzz-exercise/internal/db"
+	"github.com/oggyb/muzz-exercise/internal/logger"
+	"github.com/oggyb/muzz-exercise/internal/metrics"
+	"github.com/oggyb/muzz-exercise/internal/tracing"
 	"github.com/oggyb/muzz-exercise/internal/utils/pagination"
+	"slices"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // DecisionRepository provides data access methods for the Decision model.
 // It encapsulates all queries related to likes/passes between users.
+//
+// Queries stick to portable SQL (NOT EXISTS subqueries, bound boolean
+// parameters instead of TRUE/FALSE literals) so they run unchanged on
+// MySQL, PostgreSQL and SQLite.
 type DecisionRepository struct {
-	db *gorm.DB
+	shards *ShardRouter
+	tokens *pagination.Codec
+	retry  RetryPolicy
 }
 
 // NewDecisionRepository creates a new repository bound to the given DB connection.
 func NewDecisionRepository(database *gorm.DB) *DecisionRepository {
-	return &DecisionRepository{db: database}
+	return NewShardedDecisionRepository(NewShardRouter(database))
+}
+
+// NewShardedDecisionRepository creates a repository that spreads decisions
+// across the router's shards by recipient ID.
+func NewShardedDecisionRepository(shards *ShardRouter) *DecisionRepository {
+	return &DecisionRepository{
+		shards: shards,
+		tokens: pagination.NewRandomCodec(pagination.DefaultTTL),
+		retry:  DefaultRetryPolicy,
+	}
+}
+
+// WithTokens makes the repository sign and verify pagination tokens with
+// codec instead of the default process-local random key. Servers sharing
+// traffic need the same codec keys so tokens work on every instance.
+func (r *DecisionRepository) WithTokens(codec *pagination.Codec) *DecisionRepository {
+	r.tokens = codec
+	return r
+}
+
+// WithRetry replaces DefaultRetryPolicy for transient database errors.
+func (r *DecisionRepository) WithRetry(policy RetryPolicy) *DecisionRepository {
+	r.retry = policy
+	return r
 }
 
-// CreateOrUpdateDecision inserts or updates a decision made by actor -> recipient.
+// CreateOrUpdateDecision inserts or updates a decision made by actor -> recipient
+// and returns the previous "liked" value (nil if the pair had no decision yet).
 //
 // Behavior:
-//   - If (actor_id, recipient_id) pair exists → the row is updated with the new "liked" value.
-//   - If it doesn’t exist → a new row is inserted.
+//   - Runs in a single transaction, safe under concurrent calls for the same pair.
+//   - INSERT ... ON CONFLICT DO NOTHING (ON DUPLICATE KEY UPDATE on MySQL)
+//     creates the row if it is missing; the winner of a race reports prev = nil.
+//   - Otherwise the existing row is read with SELECT ... FOR UPDATE, so the
+//     previous value is exact even when requests flip the same pair concurrently,
+//     and is updated only if the value changed.
 //   - Composite PK ensures overwrite guarantee.
+//   - Deadlocks and other transient errors run the whole transaction
+//     again (see RetryPolicy); reads of the lists and counts retry too.
+//     A connection lost during COMMIT is returned instead: the decision
+//     may be stored, and prev and the stats deltas of a second run would
+//     be computed against it.
+//   - Sharded: the transaction runs on the recipient's shard; afterwards the
+//     new value is mirrored to the actor's shard (see ShardRouter), retried
+//     like the main write. A mirror write that still fails is logged and
+//     counted in decision_mirror_failures_total, not returned: the decision
+//     is saved, and putting it again repairs the mirror.
+//   - Updates both users' user_stats counters in the same transaction
+//     (lockStats / decisionDeltas). Sharded, the actor's counters move with
+//     the mirror write; reciprocal decisions racing across two shards can
+//     drift until the next backfill.
+//   - Always writes to primaries, then pins both users' reads to the
+//     primary for the sticky window so neither reads a lagging replica.
+//
+// SQLite has no row locks; there the transaction takes the database write
+// lock up front (see db.NewDB, _txlock=immediate), which serializes writers.
 //
 // Example:
 //
@@ -36,41 +95,160 @@ func (r *DecisionRepository) CreateOrUpdateDecision(
 	actorID, recipientID uint64,
 	liked bool,
 ) (prev *bool, err error) {
-	var decision db.Decision
-	// Try to find an existing decision between actor and recipient
-	result := r.db.WithContext(ctx).
-		First(&decision, "actor_id = ? AND recipient_id = ?", actorID, recipientID)
-
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		// No existing decision → insert new
-		newDecision := db.Decision{
-			ActorID:     actorID,
-			RecipientID: recipientID,
-			Liked:       liked,
+	ctx, span := tracing.Start(ctx, "DecisionRepository.CreateOrUpdateDecision",
+		attribute.Int64("actor_id", int64(actorID)), attribute.Int64("recipient_id", int64(recipientID)))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	sameShard := r.shards.Index(actorID) == r.shards.Index(recipientID)
+
+	err = r.retry.do(ctx, "CreateOrUpdateDecision", func() error {
+		return r.createOrUpdate(ctx, actorID, recipientID, liked, sameShard, &prev)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// committed: pin reads to the primary even if the mirror write fails
+	r.shards.MarkWritten(actorID, recipientID)
+
+	if !sameShard {
+		err := r.retry.do(ctx, "mirror", func() error {
+			return r.mirror(ctx, actorID, recipientID, liked, prev)
+		})
+		if err != nil {
+			// the decision is saved; only the actor's copy is stale until
+			// the decision is put again
+			metrics.DecisionMirrorFailed()
+			logger.FromContext(ctx).ErrorContext(ctx, "decision mirror to actor shard failed",
+				"actor_id", actorID, "recipient_id", recipientID, "err", err)
 		}
-		if err := r.db.WithContext(ctx).Create(&newDecision).Error; err != nil {
-			return nil, err
+	}
+
+	// Return the previous value so the service layer can decide how to update cache
+	return prev, nil
+}
+
+// createOrUpdate is one attempt of CreateOrUpdateDecision's transaction on
+// the recipient's shard; it stores the previous value in prev.
+func (r *DecisionRepository) createOrUpdate(ctx context.Context, actorID, recipientID uint64, liked, sameShard bool, prev **bool) error {
+	return transaction(r.shards.For(recipientID).WithContext(ctx), func(tx *gorm.DB) error {
+		// Lock the stats rows this decision changes, in ID order, so
+		// concurrent decisions between the same users serialize here.
+		locks := []uint64{recipientID}
+		if sameShard {
+			locks = []uint64{min(actorID, recipientID), max(actorID, recipientID)}
 		}
-		// Return nil because there was no previous value
-		return nil, nil
-	} else if result.Error != nil {
-		// Database error while fetching decision
+		for _, id := range locks {
+			if err := lockStats(tx, id); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		if *prev, err = upsertDecision(tx, actorID, recipientID, liked); err != nil {
+			return err
+		}
+
+		reverse, err := decisionValue(tx, recipientID, actorID)
+		if err != nil {
+			return err
+		}
+		recipientDelta, actorDelta := decisionDeltas(*prev, liked, reverse)
+		if err := applyStats(tx, recipientID, recipientDelta); err != nil {
+			return err
+		}
+		if sameShard {
+			return applyStats(tx, actorID, actorDelta)
+		}
+		return nil
+	})
+}
+
+// upsertDecision writes actor → recipient inside tx and returns the previous value.
+func upsertDecision(tx *gorm.DB, actorID, recipientID uint64, liked bool) (*bool, error) {
+	// Insert if absent; a conflicting insert is a no-op and affects 0 rows
+	result := tx.Omit(clause.Associations).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&db.Decision{ActorID: actorID, RecipientID: recipientID, Liked: liked})
+	if result.Error != nil {
 		return nil, result.Error
 	}
+	if result.RowsAffected == 1 {
+		// No previous value: this call created the decision
+		return nil, nil
+	}
 
-	// Save the previous value before updating
-	prevVal := decision.Liked
+	// Existing decision → lock it and read the previous value
+	var current db.Decision
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("liked").
+		Where("actor_id = ? AND recipient_id = ?", actorID, recipientID).
+		Take(&current).Error; err != nil {
+		return nil, err
+	}
+	prev := current.Liked
 
 	// Update only if the value has changed
-	if decision.Liked != liked {
-		decision.Liked = liked
-		if err := r.db.WithContext(ctx).Save(&decision).Error; err != nil {
-			return &prevVal, err
-		}
+	if prev == liked {
+		return &prev, nil
 	}
+	return &prev, tx.Model(&db.Decision{}).
+		Where("actor_id = ? AND recipient_id = ?", actorID, recipientID).
+		Update("liked", liked).Error
+}
 
-	// Return the previous value so the service layer can decide how to update cache
-	return &prevVal, nil
+// mirror copies a decision to the actor's shard, which differs from the
+// recipient's, so the actor's own decisions are local to their shard, and
+// applies the actor's share of the stats change there.
+func (r *DecisionRepository) mirror(ctx context.Context, actorID, recipientID uint64, liked bool, prev *bool) error {
+	ctx, span := tracing.Start(ctx, "DecisionRepository.mirror",
+		attribute.Int64("actor_id", int64(actorID)), attribute.Int64("recipient_id", int64(recipientID)))
+	defer span.End()
+
+	return transaction(r.shards.For(actorID).WithContext(ctx), func(tx *gorm.DB) error {
+		if err := lockStats(tx, actorID); err != nil {
+			return err
+		}
+		err := tx.Omit(clause.Associations).
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "actor_id"}, {Name: "recipient_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"liked", "updated_at"}),
+			}).
+			Create(&db.Decision{ActorID: actorID, RecipientID: recipientID, Liked: liked}).Error
+		if err != nil {
+			return err
+		}
+
+		// the reverse decision's home copy lives on the actor's shard
+		reverse, err := decisionValue(tx, recipientID, actorID)
+		if err != nil {
+			return err
+		}
+		_, actorDelta := decisionDeltas(prev, liked, reverse)
+		return applyStats(tx, actorID, actorDelta)
+	})
+}
+
+// Page selects one page of a like list. Lists are ordered newest first by
+// (updated_at, actor_id); Since and Until narrow that range.
+type Page struct {
+	Token *string    // next or previous token of an earlier page; nil → first page
+	Limit int        // maximum number of decisions returned
+	Since *time.Time // only likes updated strictly after Since
+	Until *time.Time // only likes updated at or before Until
+}
+
+// PageTokens are the tokens returned with a page.
+//
+//   - Next continues toward older likes; nil when there are none.
+//   - Prev fetches likes newer than the first one on this page. It is set
+//     for every non-empty page, so clients can keep the top page's Prev and
+//     poll it to load new likes incrementally.
+type PageTokens struct {
+	Next *string
+	Prev *string
 }
 
 // GetLikers returns all users who liked the given recipient.
@@ -79,64 +257,25 @@ func (r *DecisionRepository) CreateOrUpdateDecision(
 //   - Only decisions where recipient_id = X and liked = true are returned.
 //   - Excludes users that the recipient explicitly passed (liked = false).
 //   - Ordered by updated_at DESC, actor_id DESC.
-//   - Supports cursor-based pagination via paginationToken.
+//   - Supports keyset pagination in both directions via page.Token; tokens
+//     are signed and only valid for this list of this recipient until they
+//     expire (pagination.ErrInvalidToken otherwise).
+//   - Reads from a replica unless the recipient took part in a recent decision.
 //
 // Example:
 //
-//	repo.GetLikers(ctx, 42, nil, 20) // list first 20 people who liked user 42
+//	repo.GetLikers(ctx, 42, Page{Limit: 20}) // list first 20 people who liked user 42
 func (r *DecisionRepository) GetLikers(
 	ctx context.Context,
 	recipientID uint64,
-	paginationToken *string,
-	limit int,
-) ([]db.Decision, *string, error) {
-	var decisions []db.Decision
+	page Page,
+) ([]db.Decision, PageTokens, error) {
+	ctx, span := tracing.Start(ctx, "DecisionRepository.GetLikers", attribute.Int64("recipient_id", int64(recipientID)))
+	defer span.End()
 
-	// decode cursor if provided
-	cursor, err := pagination.Decode(getString(paginationToken))
-	if err != nil {
-		return nil, nil, err
-	}
-
-	query := r.db.WithContext(ctx).
-		Table("decisions d").
-		Where("d.recipient_id = ? AND d.liked = true", recipientID).
-		Where(`
-			NOT EXISTS (
-				SELECT 1 FROM decisions d2
-				WHERE d2.actor_id = ?
-				  AND d2.recipient_id = d.actor_id
-				  AND d2.liked = false
-			)`, recipientID).
-		Order("d.updated_at DESC, d.actor_id DESC").
-		Limit(limit + 1)
-
-	// apply cursor
-	if cursor.ActorID > 0 && cursor.UpdatedUnix > 0 {
-		ts := time.UnixMilli(cursor.UpdatedUnix)
-		query = query.Where(
-			"(d.updated_at < ? OR (d.updated_at = ? AND d.actor_id < ?))",
-			ts, ts, cursor.ActorID,
-		)
-	}
-
-	if err := query.Find(&decisions).Error; err != nil {
-		return nil, nil, err
-	}
-
-	// pagination: build next cursor if needed
-	var nextToken *string
-	if len(decisions) > limit {
-		last := decisions[limit-1]
-		token, _ := pagination.Encode(pagination.Cursor{
-			ActorID:     last.ActorID,
-			UpdatedUnix: last.UpdatedAt.UnixMilli(),
-		})
-		nextToken = &token
-		decisions = decisions[:limit]
-	}
-
-	return decisions, nextToken, nil
+	return r.list(ctx, pagination.KindLikedYou, recipientID, page, func(q *gorm.DB) *gorm.DB {
+		return q
+	})
 }
 
 // GetNewLikers returns users who liked the recipient but have not been liked back.
@@ -146,69 +285,141 @@ func (r *DecisionRepository) GetLikers(
 //   - Excludes mutual likes (recipient already liked them back).
 //   - Excludes users the recipient explicitly passed.
 //   - Ordered by updated_at DESC, actor_id DESC.
-//   - Supports cursor-based pagination.
+//   - Paginates like GetLikers; tokens are bound to this list and recipient.
+//   - Reads from a replica unless the recipient took part in a recent decision.
 //
 // Example:
 //
-//	repo.GetNewLikers(ctx, 42, nil, 20) // list first 20 one-way likes for user 42
+//	repo.GetNewLikers(ctx, 42, Page{Limit: 20}) // list first 20 one-way likes for user 42
 func (r *DecisionRepository) GetNewLikers(
 	ctx context.Context,
 	recipientID uint64,
-	paginationToken *string,
-	limit int,
-) ([]db.Decision, *string, error) {
-	var decisions []db.Decision
+	page Page,
+) ([]db.Decision, PageTokens, error) {
+	ctx, span := tracing.Start(ctx, "DecisionRepository.GetNewLikers", attribute.Int64("recipient_id", int64(recipientID)))
+	defer span.End()
 
-	cursor, err := pagination.Decode(getString(paginationToken))
+	return r.list(ctx, pagination.KindNewLikedYou, recipientID, page, func(q *gorm.DB) *gorm.DB {
+		// subquery to exclude mutual likes
+		subQuery := q.Session(&gorm.Session{NewDB: true}).
+			Table("decisions").
+			Select("1").
+			Where("actor_id = d.recipient_id AND recipient_id = d.actor_id AND liked = ?", true)
+		return q.Where("NOT EXISTS (?)", subQuery)
+	})
+}
+
+// list runs one page of a like list; filter adds the list-specific conditions.
+//
+// A forward page (no token or a Next token) walks DESC from the cursor.
+// A backward page (Prev token) walks ASC from the cursor to find the likes
+// right above it, then is reversed so every page reads newest first.
+func (r *DecisionRepository) list(
+	ctx context.Context,
+	kind pagination.Kind,
+	recipientID uint64,
+	page Page,
+	filter func(q *gorm.DB) *gorm.DB,
+) ([]db.Decision, PageTokens, error) {
+	var (
+		decisions []db.Decision
+		tokens    PageTokens
+	)
+
+	// decode cursor if provided
+	cursor, err := r.tokens.Decode(getString(page.Token), kind, recipientID)
 	if err != nil {
-		return nil, nil, err
+		return nil, tokens, err
 	}
+	hasCursor := cursor.ActorID > 0 && cursor.UpdatedUnix > 0
 
-	// subquery to exclude mutual likes
-	subQuery := r.db.
-		Table("decisions").
-		Select("1").
-		Where("actor_id = d.recipient_id AND recipient_id = d.actor_id AND liked = true")
-
-	query := r.db.WithContext(ctx).
+	query := filter(r.shards.Reader(recipientID).WithContext(ctx).
 		Table("decisions d").
-		Where("d.recipient_id = ? AND d.liked = true AND NOT EXISTS (?)", recipientID, subQuery).
+		Where("d.recipient_id = ? AND d.liked = ?", recipientID, true).
 		Where(`
 			NOT EXISTS (
 				SELECT 1 FROM decisions d2
 				WHERE d2.actor_id = ?
 				  AND d2.recipient_id = d.actor_id
-				  AND d2.liked = false
-			)`, recipientID).
-		Order("d.updated_at DESC, d.actor_id DESC").
-		Limit(limit + 1)
+				  AND d2.liked = ?
+			)`, recipientID, false)).
+		Limit(page.Limit + 1)
+
+	if page.Since != nil {
+		query = query.Where("d.updated_at > ?", *page.Since)
+	}
+	if page.Until != nil {
+		query = query.Where("d.updated_at <= ?", *page.Until)
+	}
 
 	// apply cursor
-	if cursor.ActorID > 0 && cursor.UpdatedUnix > 0 {
-		ts := time.UnixMilli(cursor.UpdatedUnix)
+	ts := cursor.UpdatedAt()
+	switch {
+	case hasCursor && cursor.Backward:
+		query = query.Where(
+			"(d.updated_at > ? OR (d.updated_at = ? AND d.actor_id > ?))",
+			ts, ts, cursor.ActorID,
+		).Order("d.updated_at ASC, d.actor_id ASC")
+	case hasCursor:
 		query = query.Where(
 			"(d.updated_at < ? OR (d.updated_at = ? AND d.actor_id < ?))",
 			ts, ts, cursor.ActorID,
-		)
+		).Order("d.updated_at DESC, d.actor_id DESC")
+	default:
+		query = query.Order("d.updated_at DESC, d.actor_id DESC")
 	}
 
-	if err := query.Find(&decisions).Error; err != nil {
-		return nil, nil, err
+	query = query.Session(&gorm.Session{}) // reusable across retries
+	err = r.retry.do(ctx, "list", func() error {
+		decisions = nil
+		return query.Find(&decisions).Error
+	})
+	if err != nil {
+		return nil, tokens, err
 	}
 
-	// pagination: build next cursor if needed
-	var nextToken *string
-	if len(decisions) > limit {
-		last := decisions[limit-1]
-		token, _ := pagination.Encode(pagination.Cursor{
-			ActorID:     last.ActorID,
-			UpdatedUnix: last.UpdatedAt.UnixMilli(),
-		})
-		nextToken = &token
-		decisions = decisions[:limit]
+	more := len(decisions) > page.Limit
+	if more {
+		decisions = decisions[:page.Limit]
+	}
+	if hasCursor && cursor.Backward {
+		slices.Reverse(decisions)
+	}
+
+	// pagination: build tokens around the page
+	encode := func(d db.Decision, backward bool) (*string, error) {
+		cur := pagination.Cursor{
+			Kind:        kind,
+			RecipientID: recipientID,
+			ActorID:     d.ActorID,
+			Backward:    backward,
+		}
+		cur.SetUpdatedAt(d.UpdatedAt)
+		token, err := r.tokens.Encode(cur)
+		return &token, err
+	}
+
+	if len(decisions) == 0 {
+		if hasCursor {
+			// nothing newer (or older) yet: hand the same position back to poll
+			anchor := db.Decision{ActorID: cursor.ActorID, UpdatedAt: ts}
+			tokens.Prev, err = encode(anchor, true)
+		}
+		return decisions, tokens, err
 	}
 
-	return decisions, nextToken, nil
+	// older likes remain after a full forward page, and always after a
+	// backward page (at least the item its token was issued from)
+	if more || (hasCursor && cursor.Backward) {
+		if tokens.Next, err = encode(decisions[len(decisions)-1], false); err != nil {
+			return nil, tokens, err
+		}
+	}
+	if tokens.Prev, err = encode(decisions[0], true); err != nil {
+		return nil, tokens, err
+	}
+
+	return decisions, tokens, nil
 }
 
 // CountLikers returns how many users liked the given recipient.
@@ -217,6 +428,8 @@ func (r *DecisionRepository) GetNewLikers(
 //   - Counts only decisions where recipient_id = X and liked = true.
 //   - Excludes users that recipient explicitly passed.
 //   - Used in conjunction with Redis cache (DB is fallback).
+//   - Reads from a replica unless the recipient wrote or was liked recently,
+//     so a stale replica count is not cached right after a change.
 //
 // Example:
 //
@@ -225,18 +438,23 @@ func (r *DecisionRepository) CountLikers(
 	ctx context.Context,
 	recipientID uint64,
 ) (int64, error) {
+	ctx, span := tracing.Start(ctx, "DecisionRepository.CountLikers", attribute.Int64("recipient_id", int64(recipientID)))
+	defer span.End()
+
 	var count int64
-	err := r.db.WithContext(ctx).
-		Table("decisions d").
-		Where("d.recipient_id = ? AND d.liked = true", recipientID).
-		Where(`
-			NOT EXISTS (
-				SELECT 1 FROM decisions d2
-				WHERE d2.actor_id = ?
-				  AND d2.recipient_id = d.actor_id
-				  AND d2.liked = false
-			)`, recipientID).
-		Count(&count).Error
+	err := r.retry.do(ctx, "CountLikers", func() error {
+		return r.shards.Reader(recipientID).WithContext(ctx).
+			Table("decisions d").
+			Where("d.recipient_id = ? AND d.liked = ?", recipientID, true).
+			Where(`
+				NOT EXISTS (
+					SELECT 1 FROM decisions d2
+					WHERE d2.actor_id = ?
+					  AND d2.recipient_id = d.actor_id
+					  AND d2.liked = ?
+				)`, recipientID, false).
+			Count(&count).Error
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -249,6 +467,8 @@ func (r *DecisionRepository) CountLikers(
 //   - Returns true if there exists a decision row where actor_id = X,
 //     recipient_id = Y, and liked = true.
 //   - Used for checking mutual likes in PutDecision.
+//   - Reads the recipient's shard, where the decision's home copy lives,
+//     always on the primary: the mutual check must see the latest write.
 //
 // Example:
 //
@@ -257,11 +477,17 @@ func (r *DecisionRepository) HasLiked(
 	ctx context.Context,
 	actorID, recipientID uint64,
 ) (bool, error) {
+	ctx, span := tracing.Start(ctx, "DecisionRepository.HasLiked",
+		attribute.Int64("actor_id", int64(actorID)), attribute.Int64("recipient_id", int64(recipientID)))
+	defer span.End()
+
 	var count int64
-	err := r.db.WithContext(ctx).
-		Table("decisions d").
-		Where("d.actor_id = ? AND d.recipient_id = ? AND d.liked = true", actorID, recipientID).
-		Count(&count).Error
+	err := r.retry.do(ctx, "HasLiked", func() error {
+		return r.shards.For(recipientID).WithContext(ctx).
+			Table("decisions d").
+			Where("d.actor_id = ? AND d.recipient_id = ? AND d.liked = ?", actorID, recipientID, true).
+			Count(&count).Error
+	})
 	return count > 0, err
 }
 
@@ -2,15 +2,22 @@ package repository_test
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/oggyb/muzz-exercise/internal/config"
 	"github.com/oggyb/muzz-exercise/internal/db"
+	"github.com/oggyb/muzz-exercise/internal/db/migrate"
 	"github.com/oggyb/muzz-exercise/internal/repository"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 // setup in-memory DB
@@ -22,7 +29,30 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Fatalf("failed to open sqlite: %v", err)
 	}
-	if err := database.AutoMigrate(&db.Decision{}); err != nil {
+	if err := database.AutoMigrate(&db.Decision{}, &db.UserStats{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return database
+}
+
+// setupFileDB opens a migrated SQLite file through db.NewDB.
+// Unlike ":memory:", every pooled connection sees the same database,
+// which the concurrency tests need.
+func setupFileDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.DB.Driver = db.DriverSQLite
+	cfg.DB.SQLitePath = filepath.Join(t.TempDir(), "muzz.db")
+	database, err := db.NewDB(cfg)
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	database.Logger = logger.Default.LogMode(logger.Silent)
+	m, err := migrate.New(database)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
 		t.Fatalf("failed to migrate: %v", err)
 	}
 	return database
@@ -34,11 +64,11 @@ func TestCreateOrUpdateDecision(t *testing.T) {
 	repo := repository.NewDecisionRepository(dbase)
 
 	// insert like
-	err := repo.CreateOrUpdateDecision(ctx, 1, 2, true)
+	_, err := repo.CreateOrUpdateDecision(ctx, 1, 2, true)
 	assert.NoError(t, err)
 
 	// overwrite with pass
-	err = repo.CreateOrUpdateDecision(ctx, 1, 2, false)
+	_, err = repo.CreateOrUpdateDecision(ctx, 1, 2, false)
 	assert.NoError(t, err)
 
 	var d db.Decision
@@ -52,12 +82,12 @@ func TestGetLikersAndPagination(t *testing.T) {
 	repo := repository.NewDecisionRepository(dbase)
 
 	// actors 1,2 liked recipient 99
-	_ = repo.CreateOrUpdateDecision(ctx, 1, 99, true)
-	_ = repo.CreateOrUpdateDecision(ctx, 2, 99, true)
+	_, _ = repo.CreateOrUpdateDecision(ctx, 1, 99, true)
+	_, _ = repo.CreateOrUpdateDecision(ctx, 2, 99, true)
 	// recipient passed actor 2 → exclude
-	_ = repo.CreateOrUpdateDecision(ctx, 99, 2, false)
+	_, _ = repo.CreateOrUpdateDecision(ctx, 99, 2, false)
 
-	decisions, _, err := repo.GetLikers(ctx, 99, nil, 10)
+	decisions, _, err := repo.GetLikers(ctx, 99, repository.Page{Limit: 10})
 	assert.NoError(t, err)
 	assert.Len(t, decisions, 1)
 	assert.Equal(t, uint64(1), decisions[0].ActorID)
@@ -69,14 +99,192 @@ func TestGetNewLikers(t *testing.T) {
 	repo := repository.NewDecisionRepository(dbase)
 
 	// actor 1 liked 99, and 99 liked back → mutual
-	_ = repo.CreateOrUpdateDecision(ctx, 1, 99, true)
-	_ = repo.CreateOrUpdateDecision(ctx, 99, 1, true)
+	_, _ = repo.CreateOrUpdateDecision(ctx, 1, 99, true)
+	_, _ = repo.CreateOrUpdateDecision(ctx, 99, 1, true)
 
 	// actor 2 liked 99, but not mutual
-	_ = repo.CreateOrUpdateDecision(ctx, 2, 99, true)
+	_, _ = repo.CreateOrUpdateDecision(ctx, 2, 99, true)
 
-	decisions, _, err := repo.GetNewLikers(ctx, 99, nil, 10)
+	decisions, _, err := repo.GetNewLikers(ctx, 99, repository.Page{Limit: 10})
 	assert.NoError(t, err)
 	assert.Len(t, decisions, 1)
 	assert.Equal(t, uint64(2), decisions[0].ActorID)
 }
+
+func TestCreateOrUpdateDecisionReturnsPrevious(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewDecisionRepository(setupTestDB(t))
+
+	prev, err := repo.CreateOrUpdateDecision(ctx, 1, 2, true)
+	assert.NoError(t, err)
+	assert.Nil(t, prev)
+
+	prev, err = repo.CreateOrUpdateDecision(ctx, 1, 2, true)
+	assert.NoError(t, err)
+	if assert.NotNil(t, prev) {
+		assert.True(t, *prev)
+	}
+
+	prev, err = repo.CreateOrUpdateDecision(ctx, 1, 2, false)
+	assert.NoError(t, err)
+	if assert.NotNil(t, prev) {
+		assert.True(t, *prev)
+	}
+}
+
+// TestCreateOrUpdateDecisionConcurrent hammers a single pair from many
+// goroutines. Every call must succeed, exactly one must see no previous
+// value, and replaying the reported (prev → new) transitions must add up
+// to the final row, which is what keeps the like counters correct.
+func TestCreateOrUpdateDecisionConcurrent(t *testing.T) {
+	ctx := context.Background()
+	dbase := setupFileDB(t)
+	repo := repository.NewDecisionRepository(dbase)
+
+	// users must exist for the foreign keys
+	for _, id := range []uint64{1, 2} {
+		u := db.User{ID: id, Username: fmt.Sprintf("u%d", id), Email: fmt.Sprintf("u%d@test.com", id), PasswordHash: "x", Gender: "x"}
+		if err := dbase.Create(&u).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+
+	const workers, perWorker = 8, 25
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		inserts  int
+		netLikes int
+		start    = make(chan struct{})
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			<-start
+			for i := 0; i < perWorker; i++ {
+				liked := (w+i)%2 == 0
+				prev, err := repo.CreateOrUpdateDecision(ctx, 1, 2, liked)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				delta := 0
+				if liked {
+					delta++
+				}
+				mu.Lock()
+				if prev == nil {
+					inserts++
+				} else if *prev {
+					delta--
+				}
+				netLikes += delta
+				mu.Unlock()
+			}
+		}(w)
+	}
+	close(start)
+	wg.Wait()
+
+	var d db.Decision
+	assert.NoError(t, dbase.First(&d, "actor_id = ? AND recipient_id = ?", 1, 2).Error)
+
+	want := 0
+	if d.Liked {
+		want = 1
+	}
+	assert.Equal(t, 1, inserts)
+	assert.Equal(t, want, netLikes)
+}
+
+func TestGetLikersBidirectionalPagination(t *testing.T) {
+	ctx := context.Background()
+	dbase := setupTestDB(t)
+	repo := repository.NewDecisionRepository(dbase)
+
+	// actor i liked 99 at t0 + i seconds
+	t0 := time.Now().UTC().Truncate(time.Millisecond).Add(-time.Hour)
+	like := func(actor uint64) {
+		require.NoError(t, dbase.Create(&db.Decision{
+			ActorID: actor, RecipientID: 99, Liked: true,
+			UpdatedAt: t0.Add(time.Duration(actor) * time.Second),
+		}).Error)
+	}
+	for i := uint64(1); i <= 7; i++ {
+		like(i)
+	}
+	page := func(p repository.Page) ([]uint64, repository.PageTokens) {
+		t.Helper()
+		p.Limit = 3
+		decisions, tokens, err := repo.GetLikers(ctx, 99, p)
+		require.NoError(t, err)
+		return actorIDs(decisions), tokens
+	}
+
+	first, firstTokens := page(repository.Page{})
+	assert.Equal(t, []uint64{7, 6, 5}, first)
+	require.NotNil(t, firstTokens.Prev)
+
+	second, secondTokens := page(repository.Page{Token: firstTokens.Next})
+	assert.Equal(t, []uint64{4, 3, 2}, second)
+
+	last, lastTokens := page(repository.Page{Token: secondTokens.Next})
+	assert.Equal(t, []uint64{1}, last)
+	assert.Nil(t, lastTokens.Next)
+
+	// going back from the second page returns the first one
+	back, _ := page(repository.Page{Token: secondTokens.Prev})
+	assert.Equal(t, []uint64{7, 6, 5}, back)
+
+	// polling the top page's prev token: nothing yet, then only new likes
+	none, noneTokens := page(repository.Page{Token: firstTokens.Prev})
+	assert.Empty(t, none)
+	require.NotNil(t, noneTokens.Prev)
+	like(8)
+	like(9)
+	fresh, _ := page(repository.Page{Token: noneTokens.Prev})
+	assert.Equal(t, []uint64{9, 8}, fresh)
+
+	// since is exclusive, until inclusive
+	since, until := t0.Add(2*time.Second), t0.Add(5*time.Second)
+	window, _ := page(repository.Page{Since: &since, Until: &until})
+	assert.Equal(t, []uint64{5, 4, 3}, window)
+}
+
+// TestGetLikersPaginationSubMillisecond checks that cursors keep the full
+// updated_at precision: rows within one millisecond are neither repeated
+// nor skipped, in either direction.
+func TestGetLikersPaginationSubMillisecond(t *testing.T) {
+	ctx := context.Background()
+	dbase := setupTestDB(t)
+	repo := repository.NewDecisionRepository(dbase)
+
+	// actor i liked 99 at t0 + i microseconds, all within one millisecond
+	t0 := time.Now().UTC().Truncate(time.Millisecond).Add(-time.Hour)
+	for i := uint64(1); i <= 6; i++ {
+		require.NoError(t, dbase.Create(&db.Decision{
+			ActorID: i, RecipientID: 99, Liked: true,
+			UpdatedAt: t0.Add(time.Duration(i*100+i) * time.Microsecond),
+		}).Error)
+	}
+	page := func(p repository.Page) ([]uint64, repository.PageTokens) {
+		t.Helper()
+		p.Limit = 2
+		decisions, tokens, err := repo.GetLikers(ctx, 99, p)
+		require.NoError(t, err)
+		return actorIDs(decisions), tokens
+	}
+
+	first, firstTokens := page(repository.Page{})
+	assert.Equal(t, []uint64{6, 5}, first)
+	second, secondTokens := page(repository.Page{Token: firstTokens.Next})
+	assert.Equal(t, []uint64{4, 3}, second)
+	third, _ := page(repository.Page{Token: secondTokens.Next})
+	assert.Equal(t, []uint64{2, 1}, third)
+
+	back, _ := page(repository.Page{Token: secondTokens.Prev})
+	assert.Equal(t, []uint64{6, 5}, back)
+	none, _ := page(repository.Page{Token: firstTokens.Prev})
+	assert.Empty(t, none)
+}
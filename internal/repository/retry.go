@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	svcErr "github.com/oggyb/muzz-exercise/internal/errors"
+	"github.com/oggyb/muzz-exercise/internal/logger"
+	"github.com/oggyb/muzz-exercise/internal/metrics"
+)
+
+// RetryPolicy runs repository operations again after transient database
+// errors (see errors.Retryable), such as MySQL deadlocks and lock wait
+// timeouts on the decisions table.
+//
+// Behavior:
+//   - Up to Attempts tries in total; 1 or less disables retries.
+//   - Waits a random delay up to BaseDelay·2ⁿ (capped at MaxDelay) before
+//     retry n, so colliding transactions spread out ("full jitter").
+//   - Gives up early, returning the last error, when the context is done
+//     or its deadline would pass during the wait.
+//   - Counts retries and give-ups in db_retries_total and
+//     db_retry_giveups_total, and adds a "db.retry" event to the span.
+type RetryPolicy struct {
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy is used unless WithRetry sets another.
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, BaseDelay: 25 * time.Millisecond, MaxDelay: time.Second}
+
+// do runs fn until it succeeds, fails permanently or the policy gives up.
+// fn must be safe to run again: a whole transaction run with transaction,
+// or a read.
+func (p RetryPolicy) do(ctx context.Context, op string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !svcErr.Retryable(err) {
+			return err
+		}
+		if attempt >= p.Attempts {
+			if p.Attempts > 1 {
+				metrics.DBRetryGaveUp(op, "attempts")
+				logger.FromContext(ctx).WarnContext(ctx, "database retries exhausted",
+					"operation", op, "attempts", attempt, "err", err)
+			}
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			metrics.DBRetryGaveUp(op, "deadline")
+			return err
+		}
+		metrics.DBRetried(op)
+		trace.SpanFromContext(ctx).AddEvent("db.retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.String("error", err.Error()),
+		))
+		logger.FromContext(ctx).DebugContext(ctx, "retrying transient database error",
+			"operation", op, "attempt", attempt, "delay", delay, "err", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			metrics.DBRetryGaveUp(op, "deadline")
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// transaction runs fn in a transaction on database, like gorm's
+// Transaction, but marks an error from COMMIT with errors.CommitFailed:
+// after a lost connection there the transaction may have committed, so
+// RetryPolicy must not run it again.
+func transaction(database *gorm.DB, fn func(tx *gorm.DB) error) error {
+	committing := false
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		committing = true
+		return nil
+	})
+	if err != nil && committing {
+		return svcErr.CommitFailed(err)
+	}
+	return err
+}
+
+// backoff returns the jittered delay before retry n (1-based).
+func (p RetryPolicy) backoff(n int) time.Duration {
+	ceiling := p.MaxDelay
+	if shift := n - 1; shift < 32 {
+		if d := p.BaseDelay << shift; d > 0 && d < ceiling {
+			ceiling = d
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling) + 1
+}
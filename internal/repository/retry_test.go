@@ -0,0 +1,102 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/oggyb/muzz-exercise/internal/db"
+	"github.com/oggyb/muzz-exercise/internal/repository"
+)
+
+// failFirst makes the first n statements of the given callback kind on
+// the decisions table fail with err, the way a MySQL deadlock would, and
+// counts those statements.
+func failFirst(t *testing.T, database *gorm.DB, kind string, n int32, err error) *atomic.Int32 {
+	t.Helper()
+	var calls atomic.Int32
+	inject := func(tx *gorm.DB) {
+		table := tx.Statement.Table
+		if tx.Statement.TableExpr != nil {
+			table = tx.Statement.TableExpr.SQL // "decisions d": Table is the alias
+		}
+		if !strings.HasPrefix(table, "decisions") {
+			return
+		}
+		if calls.Add(1) <= n {
+			_ = tx.AddError(err)
+		}
+	}
+	var cbErr error
+	switch kind {
+	case "create":
+		cbErr = database.Callback().Create().Before("gorm:create").Register("test:fail_first", inject)
+	case "query":
+		cbErr = database.Callback().Query().Before("gorm:query").Register("test:fail_first", inject)
+	}
+	require.NoError(t, cbErr)
+	return &calls
+}
+
+var deadlock = &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+var fastRetry = repository.RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestRetryTransientWrite(t *testing.T) {
+	ctx := context.Background()
+	database := setupTestDB(t)
+	calls := failFirst(t, database, "create", 2, deadlock)
+	repo := repository.NewDecisionRepository(database).WithRetry(fastRetry)
+
+	prev, err := repo.CreateOrUpdateDecision(ctx, 1, 2, true)
+	require.NoError(t, err)
+	assert.Nil(t, prev, "the failed attempts were rolled back")
+	assert.Equal(t, int32(3), calls.Load())
+
+	var stats db.UserStats
+	require.NoError(t, database.First(&stats, "user_id = ?", 2).Error)
+	assert.Equal(t, int64(1), stats.LikedYou, "stats applied once")
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("after the attempts", func(t *testing.T) {
+		database := setupTestDB(t)
+		calls := failFirst(t, database, "query", 10, deadlock)
+		_, err := repository.NewDecisionRepository(database).WithRetry(fastRetry).CountLikers(ctx, 2)
+		assert.ErrorIs(t, err, deadlock)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("on permanent errors", func(t *testing.T) {
+		database := setupTestDB(t)
+		permanent := errors.New("syntax error")
+		calls := failFirst(t, database, "query", 10, permanent)
+		_, err := repository.NewDecisionRepository(database).WithRetry(fastRetry).CountLikers(ctx, 2)
+		assert.ErrorIs(t, err, permanent)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("before the deadline", func(t *testing.T) {
+		database := setupTestDB(t)
+		calls := failFirst(t, database, "query", 10, deadlock)
+		slow := repository.RetryPolicy{Attempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+
+		start := time.Now()
+		_, err := repository.NewDecisionRepository(database).WithRetry(slow).HasLiked(ctx, 1, 2)
+		assert.ErrorIs(t, err, deadlock)
+		assert.Equal(t, int32(1), calls.Load())
+		assert.Less(t, time.Since(start), 500*time.Millisecond, "did not wait past the deadline")
+	})
+}
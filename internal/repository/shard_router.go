@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultStickyWindow is how long a user's reads stay on the primary after
+// a write when no other window is configured.
+const DefaultStickyWindow = 5 * time.Second
+
+// ShardRouter maps a user ID to the database that owns that user's decisions.
+//
+// Placement:
+//   - Every decision lives on the shard of its recipient (ID % N), so
+//     "who liked me" lists and counts are single-shard queries.
+//   - A mirror copy is written to the actor's shard as well. The
+//     NOT EXISTS filters on the recipient's own decisions (passes and
+//     likes back) therefore also stay on one shard.
+//   - Mutual checks read the reverse decision from its own home shard,
+//     which may be a second lookup on a different database.
+//
+// Replicas:
+//   - Each shard may have read replicas (SetReplicas). Reader picks one
+//     round-robin; For always returns the primary.
+//   - After MarkWritten(userID), Reader returns the primary for that user
+//     until the sticky window passes, hiding replication lag from them.
+//   - Stickiness is tracked in process memory, so it holds per server
+//     instance; pick a window above the usual replica lag.
+//
+// Routing is plain modulo: changing the number of shards requires
+// moving data, so pick N with headroom.
+type ShardRouter struct {
+	shards   []*gorm.DB
+	replicas [][]*gorm.DB
+	next     atomic.Uint64
+
+	window    time.Duration
+	mu        sync.Mutex
+	written   map[uint64]time.Time // userID → end of sticky window
+	lastSweep time.Time
+}
+
+// NewShardRouter creates a router over the given shards (at least one).
+// A single shard behaves exactly like an unsharded database.
+func NewShardRouter(shards ...*gorm.DB) *ShardRouter {
+	if len(shards) == 0 {
+		panic("repository: ShardRouter needs at least one shard")
+	}
+	return &ShardRouter{
+		shards:   shards,
+		replicas: make([][]*gorm.DB, len(shards)),
+		window:   DefaultStickyWindow,
+		written:  make(map[uint64]time.Time),
+	}
+}
+
+// SetReplicas registers the read replicas of shard index.
+func (r *ShardRouter) SetReplicas(index int, replicas ...*gorm.DB) {
+	r.replicas[index] = replicas
+}
+
+// SetStickyWindow changes how long reads stay on the primary after a write.
+func (r *ShardRouter) SetStickyWindow(d time.Duration) {
+	r.window = d
+}
+
+// Index returns the shard index owning userID.
+func (r *ShardRouter) Index(userID uint64) int {
+	return int(userID % uint64(len(r.shards)))
+}
+
+// For returns the primary of the shard owning userID.
+func (r *ShardRouter) For(userID uint64) *gorm.DB {
+	return r.shards[r.Index(userID)]
+}
+
+// Reader returns a connection for read-only queries on userID's shard:
+// a replica when one is configured and userID has not written recently,
+// the primary otherwise.
+func (r *ShardRouter) Reader(userID uint64) *gorm.DB {
+	replicas := r.replicas[r.Index(userID)]
+	if len(replicas) == 0 || r.isSticky(userID) {
+		return r.For(userID)
+	}
+	return replicas[r.next.Add(1)%uint64(len(replicas))]
+}
+
+// MarkWritten pins userID's reads to the primary for the sticky window.
+func (r *ShardRouter) MarkWritten(userIDs ...uint64) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range userIDs {
+		r.written[id] = now.Add(r.window)
+	}
+
+	// Drop expired entries at most once per window to bound memory
+	if now.Sub(r.lastSweep) >= r.window {
+		for id, until := range r.written {
+			if !now.Before(until) {
+				delete(r.written, id)
+			}
+		}
+		r.lastSweep = now
+	}
+}
+
+func (r *ShardRouter) isSticky(userID uint64) bool {
+	r.mu.Lock()
+	until, ok := r.written[userID]
+	r.mu.Unlock()
+	return ok && time.Now().Before(until)
+}
+
+// Len returns the number of shards.
+func (r *ShardRouter) Len() int {
+	return len(r.shards)
+}
+
+// All returns every shard primary, in index order.
+func (r *ShardRouter) All() []*gorm.DB {
+	return r.shards
+}
@@ -0,0 +1,167 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/oggyb/muzz-exercise/internal/config"
+	"github.com/oggyb/muzz-exercise/internal/db"
+	"github.com/oggyb/muzz-exercise/internal/db/migrate"
+	"github.com/oggyb/muzz-exercise/internal/repository"
+)
+
+// setupShards opens n SQLite files migrated with the shard schema
+func setupShards(t *testing.T, n int) []*gorm.DB {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.DB.Driver = db.DriverSQLite
+	for i := 0; i < n; i++ {
+		cfg.DB.ShardDSNs = append(cfg.DB.ShardDSNs, filepath.Join(t.TempDir(), "shard.db"))
+	}
+	shards, err := db.NewShards(cfg)
+	require.NoError(t, err)
+
+	for _, shard := range shards {
+		shard.Logger = logger.Default.LogMode(logger.Silent)
+		m, err := migrate.NewShard(shard)
+		require.NoError(t, err)
+		_, err = m.Up(context.Background())
+		require.NoError(t, err)
+	}
+	return shards
+}
+
+func TestShardRouterIndex(t *testing.T) {
+	router := repository.NewShardRouter(setupShards(t, 3)...)
+	assert.Equal(t, 3, router.Len())
+	assert.Equal(t, 0, router.Index(3))
+	assert.Equal(t, 1, router.Index(4))
+	assert.Equal(t, 2, router.Index(5))
+	assert.Same(t, router.All()[1], router.For(7))
+}
+
+// TestShardedDecisions runs the same scenario as the single-DB tests across
+// three shards and checks that lists and counts only need the recipient's shard.
+func TestShardedDecisions(t *testing.T) {
+	ctx := context.Background()
+	shards := setupShards(t, 3)
+	repo := repository.NewShardedDecisionRepository(repository.NewShardRouter(shards...))
+
+	// recipient 3 lives on shard 0; actors 1 and 4 on shard 1, actor 2 on shard 2
+	for _, d := range []struct {
+		actor, recipient uint64
+		liked            bool
+	}{
+		{1, 3, true},
+		{2, 3, true},
+		{4, 3, true},
+		{3, 2, false}, // 3 passed 2 → 2 excluded from 3's lists
+		{3, 4, true},  // 3 liked 4 back → mutual, excluded from "new"
+	} {
+		_, err := repo.CreateOrUpdateDecision(ctx, d.actor, d.recipient, d.liked)
+		require.NoError(t, err)
+	}
+
+	likers, _, err := repo.GetLikers(ctx, 3, repository.Page{Limit: 10})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint64{1, 4}, actorIDs(likers))
+
+	newLikers, _, err := repo.GetNewLikers(ctx, 3, repository.Page{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1}, actorIDs(newLikers))
+
+	count, err := repo.CountLikers(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// mutual check reads 4's shard for the 3 → 4 decision
+	mutual, err := repo.HasLiked(ctx, 3, 4)
+	require.NoError(t, err)
+	assert.True(t, mutual)
+
+	// shard 0 holds everything recipient 3 needs: received decisions and mirrors of sent ones
+	var onHome int64
+	require.NoError(t, shards[0].Table("decisions").
+		Where("recipient_id = ? OR actor_id = ?", 3, 3).Count(&onHome).Error)
+	assert.Equal(t, int64(5), onHome)
+
+	// shard 2 only knows about user 2
+	var onOther int64
+	require.NoError(t, shards[2].Table("decisions").Count(&onOther).Error)
+	assert.Equal(t, int64(2), onOther)
+}
+
+// TestShardedMirrorFailure checks that a failing mirror write leaves the
+// saved decision in place and still pins both users to the primary.
+func TestShardedMirrorFailure(t *testing.T) {
+	ctx := context.Background()
+	dbs := setupShards(t, 3)
+	router := repository.NewShardRouter(dbs[0], dbs[1])
+	router.SetReplicas(0, dbs[2])
+	router.SetStickyWindow(time.Minute)
+	repo := repository.NewShardedDecisionRepository(router).WithRetry(fastRetry)
+
+	// actor 1 lives on shard 1, recipient 2 on shard 0
+	calls := failFirst(t, dbs[1], "create", 10, errors.New("disk full"))
+	prev, err := repo.CreateOrUpdateDecision(ctx, 1, 2, true)
+	require.NoError(t, err, "the decision is saved on the recipient's shard")
+	assert.Nil(t, prev)
+	assert.Equal(t, int32(1), calls.Load(), "permanent errors are not retried")
+	assert.Same(t, dbs[0], router.Reader(2), "reads pinned to the primary")
+
+	likers, _, err := repo.GetLikers(ctx, 2, repository.Page{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1}, actorIDs(likers))
+}
+
+// TestReplicaReadsAndStickiness uses an unreplicated "replica" so every
+// read shows which database served it.
+func TestReplicaReadsAndStickiness(t *testing.T) {
+	ctx := context.Background()
+	dbs := setupShards(t, 2)
+	primary, replica := dbs[0], dbs[1]
+
+	router := repository.NewShardRouter(primary)
+	router.SetReplicas(0, replica)
+	router.SetStickyWindow(100 * time.Millisecond)
+	repo := repository.NewShardedDecisionRepository(router)
+
+	require.NoError(t, replica.Create(&db.Decision{ActorID: 9, RecipientID: 1, Liked: true}).Error)
+	likers, _, err := repo.GetLikers(ctx, 1, repository.Page{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{9}, actorIDs(likers), "reads go to the replica")
+
+	// the write lands on the primary and pins both users to it
+	_, err = repo.CreateOrUpdateDecision(ctx, 2, 1, true)
+	require.NoError(t, err)
+	likers, _, err = repo.GetLikers(ctx, 1, repository.Page{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2}, actorIDs(likers), "recipient reads the primary after a write")
+	assert.Same(t, primary, router.Reader(2))
+	assert.Same(t, replica, router.Reader(3), "unrelated users stay on the replica")
+
+	mutual, err := repo.HasLiked(ctx, 2, 1)
+	require.NoError(t, err)
+	assert.True(t, mutual, "mutual check always reads the primary")
+
+	// once the window passes reads return to the replica
+	assert.Eventually(t, func() bool {
+		return router.Reader(1) == replica && router.Reader(2) == replica
+	}, time.Second, 20*time.Millisecond)
+}
+
+func actorIDs(decisions []db.Decision) []uint64 {
+	ids := make([]uint64, len(decisions))
+	for i, d := range decisions {
+		ids[i] = d.ActorID
+	}
+	return ids
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/oggyb/muzz-exercise/internal/db"
+	"github.com/oggyb/muzz-exercise/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrUsernameTaken and ErrEmailTaken report a clash with the unique
+// indexes on users.username and users.email.
+var (
+	ErrUsernameTaken = errors.New("username already taken")
+	ErrEmailTaken    = errors.New("email already registered")
+)
+
+// UserRepository provides data access methods for the User model.
+// Users always live on the main database, also when decisions are sharded.
+type UserRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository creates a new repository bound to the given DB connection.
+func NewUserRepository(database *gorm.DB) *UserRepository {
+	return &UserRepository{db: database}
+}
+
+// Create inserts a new user.
+//
+// Behavior:
+//   - Expects username and email already normalized (lowercase, trimmed).
+//   - Returns ErrUsernameTaken / ErrEmailTaken instead of the driver's
+//     unique-violation error; a clash found only by the insert (a
+//     concurrent registration) is looked up again to tell which one.
+//
+// Example:
+//
+//	err := repo.Create(ctx, &db.User{Username: "alice", Email: "a@x.io", ...})
+func (r *UserRepository) Create(ctx context.Context, user *db.User) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.Create")
+	defer span.End()
+
+	if err := r.checkTaken(ctx, user.Username, user.Email); err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
+		if taken := r.checkTaken(ctx, user.Username, user.Email); taken != nil {
+			return taken
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *UserRepository) checkTaken(ctx context.Context, username, email string) error {
+	var existing []db.User
+	err := r.db.WithContext(ctx).
+		Select("username", "email").
+		Where("username = ? OR email = ?", username, email).
+		Limit(2).
+		Find(&existing).Error
+	if err != nil {
+		return err
+	}
+	for _, u := range existing {
+		if u.Username == username {
+			return ErrUsernameTaken
+		}
+	}
+	if len(existing) > 0 {
+		return ErrEmailTaken
+	}
+	return nil
+}
+
+// FindByLogin returns the user whose username or email equals login
+// (gorm.ErrRecordNotFound if none).
+//
+// Example:
+//
+//	user, err := repo.FindByLogin(ctx, "alice")
+func (r *UserRepository) FindByLogin(ctx context.Context, login string) (*db.User, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.FindByLogin")
+	defer span.End()
+
+	var user db.User
+	err := r.db.WithContext(ctx).
+		Where("username = ? OR email = ?", login, login).
+		Take(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindByID returns the user with the given ID (gorm.ErrRecordNotFound if none).
+func (r *UserRepository) FindByID(ctx context.Context, id uint64) (*db.User, error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.FindByID", attribute.Int64("user_id", int64(id)))
+	defer span.End()
+
+	var user db.User
+	if err := r.db.WithContext(ctx).Take(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// TouchLastLogin records a successful login.
+func (r *UserRepository) TouchLastLogin(ctx context.Context, id uint64, at time.Time) error {
+	ctx, span := tracing.Start(ctx, "UserRepository.TouchLastLogin", attribute.Int64("user_id", int64(id)))
+	defer span.End()
+
+	return r.db.WithContext(ctx).
+		Model(&db.User{}).
+		Where("id = ?", id).
+		Update("last_login_at", at).Error
+}
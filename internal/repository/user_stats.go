@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/oggyb/muzz-exercise/internal/db"
+	"github.com/oggyb/muzz-exercise/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// statsDelta is the change a single decision makes to one user's counters.
+type statsDelta struct {
+	likedYou, newLikedYou, matches int64
+}
+
+func (d statsDelta) zero() bool {
+	return d.likedYou == 0 && d.newLikedYou == 0 && d.matches == 0
+}
+
+// decisionDeltas returns how the decision actor → recipient changing from
+// prev (nil: no decision) to liked moves both users' counters, given the
+// reverse decision recipient → actor.
+func decisionDeltas(prev *bool, liked bool, reverse *bool) (recipient, actor statsDelta) {
+	next := &liked
+	diff := func(f func(x *bool) bool) int64 { return b2i(f(next)) - b2i(f(prev)) }
+
+	match := diff(func(x *bool) bool { return isTrue(x) && isTrue(reverse) })
+	recipient = statsDelta{
+		// the recipient counts the actor's like unless they passed the actor
+		likedYou:    diff(func(x *bool) bool { return isTrue(x) && !isFalse(reverse) }),
+		newLikedYou: diff(func(x *bool) bool { return isTrue(x) && reverse == nil }),
+		matches:     match,
+	}
+	actor = statsDelta{
+		// the actor counts the recipient's like unless the actor passed them
+		likedYou:    diff(func(x *bool) bool { return isTrue(reverse) && !isFalse(x) }),
+		newLikedYou: diff(func(x *bool) bool { return isTrue(reverse) && x == nil }),
+		matches:     match,
+	}
+	return recipient, actor
+}
+
+// lockStats locks userID's stats row for the rest of tx, creating it from
+// the decisions table first if it does not exist yet. Call it before
+// changing any decision involving userID.
+func lockStats(tx *gorm.DB, userID uint64) error {
+	locked := func() (bool, error) {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userID).
+			Limit(1).
+			Find(&db.UserStats{})
+		return result.RowsAffected == 1, result.Error
+	}
+
+	// Existence is checked without a lock: a locking read of a missing row
+	// takes a gap lock on MySQL, and two such inserts would deadlock.
+	var n int64
+	if err := tx.Model(&db.UserStats{}).Where("user_id = ?", userID).Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		_, err := locked()
+		return err
+	}
+
+	// First decision involving this user since stats exist: count once.
+	// A concurrent creator wins the insert and ours becomes a no-op.
+	stats, err := computeStats(tx, userID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&stats).Error; err != nil {
+		return err
+	}
+	_, err = locked()
+	return err
+}
+
+// applyStats adds delta to userID's counters; the row must be locked.
+func applyStats(tx *gorm.DB, userID uint64, delta statsDelta) error {
+	if delta.zero() {
+		return nil
+	}
+	return tx.Model(&db.UserStats{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]any{
+			"liked_you":     gorm.Expr("liked_you + ?", delta.likedYou),
+			"new_liked_you": gorm.Expr("new_liked_you + ?", delta.newLikedYou),
+			"matches":       gorm.Expr("matches + ?", delta.matches),
+		}).Error
+}
+
+// computeStats counts userID's counters from the decisions on tx's database,
+// which holds every decision involving the user (see ShardRouter).
+func computeStats(tx *gorm.DB, userID uint64) (db.UserStats, error) {
+	stats := db.UserStats{UserID: userID}
+	received := func() *gorm.DB {
+		return tx.Table("decisions d").
+			Where("d.recipient_id = ? AND d.liked = ?", userID, true)
+	}
+
+	// liked you, minus users this user passed (same as CountLikers)
+	err := received().
+		Where("NOT EXISTS (SELECT 1 FROM decisions d2 WHERE d2.actor_id = ? AND d2.recipient_id = d.actor_id AND d2.liked = ?)",
+			userID, false).
+		Count(&stats.LikedYou).Error
+	if err != nil {
+		return stats, err
+	}
+
+	// liked you, and no decision back yet
+	err = received().
+		Where("NOT EXISTS (SELECT 1 FROM decisions d2 WHERE d2.actor_id = ? AND d2.recipient_id = d.actor_id)", userID).
+		Count(&stats.NewLikedYou).Error
+	if err != nil {
+		return stats, err
+	}
+
+	// liked you, and liked back
+	err = received().
+		Where("EXISTS (SELECT 1 FROM decisions d2 WHERE d2.actor_id = ? AND d2.recipient_id = d.actor_id AND d2.liked = ?)",
+			userID, true).
+		Count(&stats.Matches).Error
+	return stats, err
+}
+
+// decisionValue returns the liked value of actor → recipient, or nil if
+// there is no such decision on tx's database.
+func decisionValue(tx *gorm.DB, actorID, recipientID uint64) (*bool, error) {
+	var d db.Decision
+	result := tx.Select("liked").
+		Where("actor_id = ? AND recipient_id = ?", actorID, recipientID).
+		Limit(1).
+		Find(&d)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+	return &d.Liked, nil
+}
+
+// GetUserStats returns the counters of userID, or nil if the user has no
+// stats row yet (no decision involving them since stats were introduced
+// and not backfilled).
+//
+// Behavior:
+//   - Single primary-key lookup on the user's shard, retried on
+//     transient errors like the other reads.
+//   - Reads from a replica unless the user took part in a recent decision.
+//
+// Example:
+//
+//	stats, _ := repo.GetUserStats(ctx, 42) // stats.LikedYou -> 123
+func (r *DecisionRepository) GetUserStats(ctx context.Context, userID uint64) (*db.UserStats, error) {
+	ctx, span := tracing.Start(ctx, "DecisionRepository.GetUserStats", attribute.Int64("user_id", int64(userID)))
+	defer span.End()
+
+	var stats db.UserStats
+	err := r.retry.do(ctx, "GetUserStats", func() error {
+		return r.shards.Reader(userID).WithContext(ctx).
+			Where("user_id = ?", userID).
+			Take(&stats).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// RecomputeUserStats recounts userID's counters from the decisions table
+// and stores them, creating the row if needed. Used by the backfill command.
+//
+// Behavior:
+//   - Runs in one transaction holding the stats row lock, so decisions
+//     involving the user wait instead of applying deltas to a stale row.
+//
+// Example:
+//
+//	stats, _ := repo.RecomputeUserStats(ctx, 42)
+func (r *DecisionRepository) RecomputeUserStats(ctx context.Context, userID uint64) (db.UserStats, error) {
+	ctx, span := tracing.Start(ctx, "DecisionRepository.RecomputeUserStats", attribute.Int64("user_id", int64(userID)))
+	defer span.End()
+
+	var stats db.UserStats
+	err := transaction(r.shards.For(userID).WithContext(ctx), func(tx *gorm.DB) error {
+		if err := lockStats(tx, userID); err != nil {
+			return err
+		}
+		var err error
+		if stats, err = computeStats(tx, userID); err != nil {
+			return err
+		}
+		return tx.Save(&stats).Error
+	})
+	return stats, err
+}
+
+func isTrue(b *bool) bool  { return b != nil && *b }
+func isFalse(b *bool) bool { return b != nil && !*b }
+
+func b2i(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,53 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oggyb/muzz-exercise/internal/repository"
+)
+
+// TestUserStatsMatchRecount applies random decisions and checks after each
+// one that the maintained counters equal a full recount.
+func TestUserStatsMatchRecount(t *testing.T) {
+	for _, n := range []int{1, 3} {
+		t.Run(fmt.Sprintf("%d shards", n), func(t *testing.T) {
+			ctx := context.Background()
+			repo := repository.NewShardedDecisionRepository(repository.NewShardRouter(setupShards(t, n)...))
+			rnd := rand.New(rand.NewSource(1))
+
+			for i := 0; i < 200; i++ {
+				actor, recipient := uint64(rnd.Intn(5)+1), uint64(rnd.Intn(5)+1)
+				if actor == recipient {
+					continue
+				}
+				_, err := repo.CreateOrUpdateDecision(ctx, actor, recipient, rnd.Intn(2) == 0)
+				require.NoError(t, err)
+
+				for _, id := range []uint64{actor, recipient} {
+					got, err := repo.GetUserStats(ctx, id)
+					require.NoError(t, err)
+					require.NotNil(t, got)
+					want, err := repo.RecomputeUserStats(ctx, id)
+					require.NoError(t, err)
+					assert.Equal(t, want.LikedYou, got.LikedYou, "liked_you of %d after %d → %d", id, actor, recipient)
+					assert.Equal(t, want.NewLikedYou, got.NewLikedYou, "new_liked_you of %d", id)
+					assert.Equal(t, want.Matches, got.Matches, "matches of %d", id)
+
+					count, err := repo.CountLikers(ctx, id)
+					require.NoError(t, err)
+					assert.Equal(t, count, got.LikedYou)
+				}
+			}
+
+			stats, err := repo.GetUserStats(ctx, 99)
+			require.NoError(t, err)
+			assert.Nil(t, stats, "users without decisions have no row")
+		})
+	}
+}
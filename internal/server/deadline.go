@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// MethodTimeouts are the default deadlines for calls arriving without one.
+type MethodTimeouts struct {
+	fallback time.Duration
+	byMethod map[string]time.Duration // full method or "/pkg.Service/"
+}
+
+// ParseMethodTimeouts returns fallback overridden by "method=duration"
+// entries, where method is a full method name
+// ("/explore.ExploreService/ListLikedYou") or a service
+// ("/explore.ExploreService/"). A zero duration disables the default.
+//
+// Example:
+//
+//	ParseMethodTimeouts(10*time.Second, []string{"/explore.ExploreService/=3s"})
+func ParseMethodTimeouts(fallback time.Duration, entries []string) (*MethodTimeouts, error) {
+	t := &MethodTimeouts{fallback: fallback, byMethod: map[string]time.Duration{}}
+	for _, e := range entries {
+		method, raw, ok := strings.Cut(e, "=")
+		method = strings.TrimSpace(method)
+		if !ok || !strings.HasPrefix(method, "/") {
+			return nil, fmt.Errorf("invalid method timeout %q, want /pkg.Service/Method=duration", e)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid method timeout %q: bad duration", e)
+		}
+		t.byMethod[method] = d
+	}
+	return t, nil
+}
+
+// timeout returns the default for method: an exact entry, else its
+// service's, else the fallback.
+func (t *MethodTimeouts) timeout(method string) time.Duration {
+	if d, ok := t.byMethod[method]; ok {
+		return d
+	}
+	if i := strings.LastIndex(method, "/"); i > 0 {
+		if d, ok := t.byMethod[method[:i+1]]; ok {
+			return d
+		}
+	}
+	return t.fallback
+}
+
+// DeadlineUnaryInterceptor bounds calls the client sent without a deadline
+// by the configured default for their method (see config GRPC.Timeout and
+// GRPC.MethodTimeouts), so a slow database cannot hold them forever.
+// Deadlines set by clients are kept, even when longer. Streams are not
+// bounded: they may legitimately stay open (e.g. health Watch).
+func DeadlineUnaryInterceptor(t *MethodTimeouts) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			if d := t.timeout(info.FullMethod); d > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, d)
+				defer cancel()
+			}
+		}
+		return handler(ctx, req)
+	}
+}
@@ -1,23 +1,126 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"github.com/oggyb/muzz-exercise/internal/config"
 	"net"
+	"slices"
+	"strings"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
+
+	"github.com/oggyb/muzz-exercise/internal/auth"
+	svcErr "github.com/oggyb/muzz-exercise/internal/errors"
+	"github.com/oggyb/muzz-exercise/internal/metrics"
+	"github.com/oggyb/muzz-exercise/internal/tracing"
 )
 
-// StartGRPCServer boots a gRPC server and registers all provided services
-func StartGRPCServer(cfg *config.Config, registrars ...Registrar) error {
+// publicMethods can be called without a token, besides those listed by
+// registrars implementing PublicRegistrar.
+var publicMethods = []string{
+	"/grpc.reflection.v1.ServerReflection/",
+	"/grpc.reflection.v1alpha.ServerReflection/",
+	"/grpc.health.v1.Health/",
+}
+
+// GRPCServer is a configured gRPC server bound to its listener.
+type GRPCServer struct {
+	srv    *grpc.Server
+	lis    net.Listener
+	health *HealthChecker
+
+	// stopWatch ends the TLS certificate reloader and the health probes.
+	stopWatch context.CancelFunc
+}
+
+// NewGRPCServer builds the server and registers all provided services.
+// Every call must carry a valid JWT (see auth.Verifier) unless
+// cfg.Auth.Disabled is set. With cfg.GRPC.TLS set the server speaks TLS
+// (mTLS with a client CA) and reloads its certificate (see CertReloader).
+//
+// Handler panics become codes.Internal, and unary calls without a deadline
+// get cfg.GRPC.Timeout (see DeadlineUnaryInterceptor).
+//
+// The standard grpc.health.v1 service is always registered; registrars
+// implementing HealthRegistrar contribute per-service probes and those
+// implementing InterceptorRegistrar their own interceptors.
+//
+// The listener is opened here, so a taken port fails before Serve.
+func NewGRPCServer(cfg *config.Config, registrars ...Registrar) (*GRPCServer, error) {
+	timeouts, err := ParseMethodTimeouts(cfg.GRPC.Timeout, cfg.GRPC.MethodTimeouts)
+	if err != nil {
+		return nil, err
+	}
+
+	// metrics, tracing and the request logger first, so calls rejected by
+	// later interceptors are counted, traced and carry a request ID; then
+	// error mapping, which hides and logs internal causes, and recovery,
+	// so panics below it are logged with that ID and counted as Internal
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			metrics.UnaryServerInterceptor(),
+			tracing.UnaryServerInterceptor(),
+			RequestLogUnaryInterceptor(),
+			svcErr.UnaryServerInterceptor(),
+			RecoveryUnaryInterceptor(),
+			DeadlineUnaryInterceptor(timeouts),
+		),
+		grpc.ChainStreamInterceptor(
+			metrics.StreamServerInterceptor(),
+			tracing.StreamServerInterceptor(),
+			RequestLogStreamInterceptor(),
+			svcErr.StreamServerInterceptor(),
+			RecoveryStreamInterceptor(),
+		),
+	}
+	if !cfg.Auth.Disabled {
+		verifier, err := auth.NewVerifier(cfg)
+		if err != nil {
+			return nil, err
+		}
+		public := slices.Clone(publicMethods)
+		for _, r := range registrars {
+			if pr, ok := r.(PublicRegistrar); ok {
+				public = append(public, pr.PublicMethods()...)
+			}
+		}
+		interceptor := auth.NewInterceptor(verifier, public...)
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(interceptor.Unary()),
+			grpc.ChainStreamInterceptor(interceptor.Stream()),
+		)
+	}
+	for _, r := range registrars {
+		if ir, ok := r.(InterceptorRegistrar); ok {
+			service, ic := ir.Interceptors()
+			for _, u := range ic.Unary {
+				opts = append(opts, grpc.ChainUnaryInterceptor(scopeUnary(service, u)))
+			}
+			for _, st := range ic.Stream {
+				opts = append(opts, grpc.ChainStreamInterceptor(scopeStream(service, st)))
+			}
+		}
+	}
+
+	reloader, err := NewCertReloader(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if reloader != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(reloader.TLSConfig())))
+	}
+
 	addr := fmt.Sprintf("%s:%s", cfg.GRPC.Host, cfg.GRPC.Port)
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(opts...)
 
 	// register all services
 	for _, r := range registrars {
@@ -27,5 +130,102 @@ func StartGRPCServer(cfg *config.Config, registrars ...Registrar) error {
 	// enable reflection for easier debugging with grpcurl
 	reflection.Register(grpcServer)
 
-	return grpcServer.Serve(lis)
+	// health checking, fed by the registrars' dependency probes
+	checker := NewHealthChecker(cfg.Health.Interval, cfg.Health.Timeout)
+	for _, r := range registrars {
+		if hr, ok := r.(HealthRegistrar); ok {
+			service, probes := hr.HealthProbes()
+			checker.Add(service, probes...)
+		}
+	}
+	healthpb.RegisterHealthServer(grpcServer, checker.server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	checker.Start(ctx)
+	if reloader != nil {
+		go reloader.Watch(ctx, cfg.GRPC.TLS.ReloadEvery)
+	}
+	return &GRPCServer{srv: grpcServer, lis: lis, health: checker, stopWatch: cancel}, nil
+}
+
+// Addr is the address the server listens on.
+func (s *GRPCServer) Addr() net.Addr {
+	return s.lis.Addr()
+}
+
+// Health returns the server's health checker, e.g. for NewHealthHTTPServer.
+func (s *GRPCServer) Health() *HealthChecker {
+	return s.health
+}
+
+// Serve accepts connections until Shutdown; it returns nil after a shutdown.
+func (s *GRPCServer) Serve() error {
+	return s.srv.Serve(s.lis)
+}
+
+// Shutdown reports NOT_SERVING, stops accepting connections and waits for
+// in-flight calls to finish. When ctx is done first, the remaining calls
+// are cancelled.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+//	defer cancel()
+//	srv.Shutdown(ctx)
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+	defer s.stopWatch()
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.srv.Stop()
+		<-done
+		return fmt.Errorf("drain timed out, in-flight calls cancelled: %w", ctx.Err())
+	}
+}
+
+// scopeUnary runs i only for methods of service ("/pkg.Service/"); an
+// empty service matches every method.
+func scopeUnary(service string, i grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	if service == "" {
+		return i
+	}
+	prefix := "/" + strings.Trim(service, "/") + "/"
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !strings.HasPrefix(info.FullMethod, prefix) {
+			return handler(ctx, req)
+		}
+		return i(ctx, req, info, handler)
+	}
+}
+
+// scopeStream is scopeUnary for stream interceptors.
+func scopeStream(service string, i grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	if service == "" {
+		return i
+	}
+	prefix := "/" + strings.Trim(service, "/") + "/"
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !strings.HasPrefix(info.FullMethod, prefix) {
+			return handler(srv, ss)
+		}
+		return i(srv, ss, info, handler)
+	}
+}
+
+// StartGRPCServer builds the server (see NewGRPCServer) and serves until it
+// fails. Prefer NewGRPCServer with Shutdown for graceful stops.
+func StartGRPCServer(cfg *config.Config, registrars ...Registrar) error {
+	s, err := NewGRPCServer(cfg, registrars...)
+	if err != nil {
+		return err
+	}
+	return s.Serve()
 }
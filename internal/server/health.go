@@ -0,0 +1,245 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/oggyb/muzz-exercise/internal/logger"
+)
+
+// Probe checks one dependency, e.g. a DB ping.
+type Probe struct {
+	// Name identifies the dependency; probes with the same name run once
+	// per round even when several services list them.
+	Name string
+	// Critical probes also decide the server's overall status ("" in
+	// grpc.health.v1, /readyz over HTTP). Leave it false for dependencies
+	// the server can run without, such as Redis behind the circuit breaker.
+	Critical bool
+	Check    func(ctx context.Context) error
+	// Detail, if set, adds a note to the probe's line in /readyz, e.g.
+	// the state of a circuit breaker. It is read after each Check.
+	Detail func() string
+}
+
+// HealthRegistrar is implemented by registrars whose service health depends
+// on probes. The service is SERVING while all of its probes pass.
+type HealthRegistrar interface {
+	// HealthProbes returns the full service name (e.g. "explore.ExploreService")
+	// and the probes it depends on.
+	HealthProbes() (service string, probes []Probe)
+}
+
+// HealthChecker runs probes periodically and publishes the result through
+// the standard grpc.health.v1 service.
+//
+// Behavior:
+//   - Overall status ("") is SERVING while every critical probe passes.
+//   - Each registered service is SERVING while all of its probes pass.
+//   - Before the first round and after Shutdown everything is NOT_SERVING.
+type HealthChecker struct {
+	server   *health.Server
+	interval time.Duration
+	timeout  time.Duration
+
+	probes   map[string]Probe
+	services map[string][]string // service → probe names
+
+	mu       sync.RWMutex
+	results  map[string]error
+	details  map[string]string
+	serving  bool
+	shutdown bool
+}
+
+// NewHealthChecker creates a checker probing every interval, each probe
+// bounded by timeout.
+func NewHealthChecker(interval, timeout time.Duration) *HealthChecker {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	h := &HealthChecker{
+		server:   health.NewServer(),
+		interval: interval,
+		timeout:  timeout,
+		probes:   map[string]Probe{},
+		services: map[string][]string{},
+		results:  map[string]error{},
+		details:  map[string]string{},
+	}
+	h.server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	return h
+}
+
+// Add registers service with the probes it depends on. Call before Start.
+func (h *HealthChecker) Add(service string, probes ...Probe) {
+	for _, p := range probes {
+		if existing, ok := h.probes[p.Name]; ok {
+			p.Critical = p.Critical || existing.Critical
+		}
+		h.probes[p.Name] = p
+		h.services[service] = append(h.services[service], p.Name)
+	}
+	h.server.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+// Start runs a first round of probes, then keeps probing in the
+// background until ctx is done.
+func (h *HealthChecker) Start(ctx context.Context) {
+	h.check(ctx)
+	go func() {
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.check(ctx)
+			}
+		}
+	}()
+}
+
+// check runs all probes in parallel and updates the statuses.
+func (h *HealthChecker) check(ctx context.Context) {
+	results := make(map[string]error, len(h.probes))
+	details := make(map[string]string, len(h.probes))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, p := range h.probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pctx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+			err := p.Check(pctx)
+			mu.Lock()
+			results[name] = err
+			if p.Detail != nil {
+				details[name] = p.Detail()
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.shutdown {
+		return
+	}
+	for name, err := range results {
+		if prev, seen := h.results[name]; !seen || (prev == nil) != (err == nil) {
+			if err != nil {
+				logger.Warn("health probe failing", "probe", name, "err", err)
+			} else if seen {
+				logger.Info("health probe recovered", "probe", name)
+			}
+		}
+	}
+	h.results = results
+	h.details = details
+
+	h.serving = true
+	for name, p := range h.probes {
+		if p.Critical && results[name] != nil {
+			h.serving = false
+		}
+	}
+	h.server.SetServingStatus("", status(h.serving))
+	for service, names := range h.services {
+		ok := true
+		for _, name := range names {
+			ok = ok && results[name] == nil
+		}
+		h.server.SetServingStatus(service, status(ok))
+	}
+}
+
+func status(serving bool) healthpb.HealthCheckResponse_ServingStatus {
+	if serving {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
+
+// Serving reports the overall status.
+func (h *HealthChecker) Serving() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.serving && !h.shutdown
+}
+
+// Shutdown marks everything NOT_SERVING for good, so load balancers stop
+// routing here while in-flight calls drain.
+func (h *HealthChecker) Shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.shutdown = true
+	h.server.Shutdown()
+}
+
+// HTTPHandler serves Kubernetes-style probes:
+//   - /livez: 200 while the process can answer at all.
+//   - /readyz: 200 while the overall status is SERVING, 503 otherwise;
+//     the body lists each probe's last result and detail.
+func (h *HealthChecker) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/livez", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		h.mu.RLock()
+		names := make([]string, 0, len(h.results))
+		for name := range h.results {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var body strings.Builder
+		for _, name := range names {
+			result := "ok"
+			if err := h.results[name]; err != nil {
+				result = err.Error()
+			}
+			if detail := h.details[name]; detail != "" {
+				result += " (" + detail + ")"
+			}
+			fmt.Fprintf(&body, "%s: %s\n", name, result)
+		}
+		ready := h.serving && !h.shutdown
+		h.mu.RUnlock()
+
+		if !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		fmt.Fprint(w, body.String())
+	})
+	return mux
+}
+
+// NewHTTPServer returns an HTTP server for handler on addr, used for the
+// health and metrics endpoints.
+//
+// Example:
+//
+//	hs := server.NewHTTPServer(":8081", grpcServer.Health().HTTPHandler())
+//	go hs.ListenAndServe()
+func NewHTTPServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
@@ -0,0 +1,110 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/oggyb/muzz-exercise/internal/config"
+	"github.com/oggyb/muzz-exercise/internal/server"
+)
+
+// probedRegistrar registers no service, only its health probes.
+type probedRegistrar struct {
+	service string
+	probes  []server.Probe
+}
+
+func (r probedRegistrar) Register(*grpc.Server) {}
+
+func (r probedRegistrar) HealthProbes() (string, []server.Probe) {
+	return r.service, r.probes
+}
+
+// TestHealthFollowsProbes checks that a failing critical probe takes the
+// whole server out of service, a failing optional one only the services
+// depending on it, and that Shutdown reports NOT_SERVING.
+func TestHealthFollowsProbes(t *testing.T) {
+	var dbDown, redisDown atomic.Bool
+	probe := func(name string, critical bool, down *atomic.Bool) server.Probe {
+		return server.Probe{Name: name, Critical: critical, Check: func(context.Context) error {
+			if down.Load() {
+				return errors.New(name + " down")
+			}
+			return nil
+		}}
+	}
+
+	redis := probe("redis", false, &redisDown)
+	redis.Detail = func() string { return "breaker closed" }
+
+	cfg := config.New()
+	cfg.GRPC.Host, cfg.GRPC.Port = "127.0.0.1", "0"
+	cfg.Auth.Disabled = true
+	cfg.Health.Interval = 10 * time.Millisecond
+	srv, err := server.NewGRPCServer(cfg,
+		probedRegistrar{"test.Explore", []server.Probe{probe("db", true, &dbDown)}},
+		probedRegistrar{"test.Auth", []server.Probe{probe("db", true, &dbDown), redis}},
+	)
+	require.NoError(t, err)
+	go srv.Serve()
+
+	conn, err := grpc.NewClient(srv.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	var readyzBody string
+	readyz := func() int {
+		rec := httptest.NewRecorder()
+		srv.Health().HTTPHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		readyzBody = rec.Body.String()
+		return rec.Code
+	}
+	expect := func(overall, explore, auth healthpb.HealthCheckResponse_ServingStatus) {
+		t.Helper()
+		assert.EventuallyWithT(t, func(c *assert.CollectT) {
+			for service, want := range map[string]healthpb.HealthCheckResponse_ServingStatus{
+				"": overall, "test.Explore": explore, "test.Auth": auth,
+			} {
+				resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+				if assert.NoError(c, err) {
+					assert.Equal(c, want, resp.Status, "service %q", service)
+				}
+			}
+		}, time.Second, 10*time.Millisecond)
+	}
+	const up, down = healthpb.HealthCheckResponse_SERVING, healthpb.HealthCheckResponse_NOT_SERVING
+
+	expect(up, up, up)
+	assert.Equal(t, http.StatusOK, readyz())
+	assert.Equal(t, "db: ok\nredis: ok (breaker closed)\n", readyzBody)
+
+	redisDown.Store(true)
+	expect(up, up, down)
+	assert.Equal(t, http.StatusOK, readyz())
+	assert.Contains(t, readyzBody, "redis: redis down (breaker closed)")
+
+	dbDown.Store(true)
+	expect(down, down, down)
+	assert.Equal(t, http.StatusServiceUnavailable, readyz())
+
+	dbDown.Store(false)
+	redisDown.Store(false)
+	expect(up, up, up)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, srv.Shutdown(ctx))
+	assert.Equal(t, http.StatusServiceUnavailable, readyz())
+}
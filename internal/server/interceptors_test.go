@@ -0,0 +1,118 @@
+package server_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/oggyb/muzz-exercise/internal/config"
+	"github.com/oggyb/muzz-exercise/internal/server"
+)
+
+// testRegistrar serves test.Service with a Panic and a Deadline method and
+// counts the calls its own interceptor sees.
+type testRegistrar struct {
+	deadline  chan time.Duration // remaining time seen by Deadline
+	intercept atomic.Int32
+}
+
+func (r *testRegistrar) Register(s *grpc.Server) {
+	method := func(name string, h func(ctx context.Context) error) grpc.MethodDesc {
+		return grpc.MethodDesc{MethodName: name, Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			in := new(emptypb.Empty)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, _ any) (any, error) { return new(emptypb.Empty), h(ctx) }
+			if interceptor == nil {
+				return handler(ctx, in)
+			}
+			return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Service/" + name}, handler)
+		}}
+	}
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Service",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			method("Panic", func(context.Context) error { panic("boom") }),
+			method("Deadline", func(ctx context.Context) error {
+				d, ok := ctx.Deadline()
+				if !ok {
+					r.deadline <- 0
+				} else {
+					r.deadline <- time.Until(d)
+				}
+				return nil
+			}),
+		},
+	}, struct{}{})
+}
+
+func (r *testRegistrar) Interceptors() (string, server.Interceptors) {
+	return "test.Service", server.Interceptors{Unary: []grpc.UnaryServerInterceptor{
+		func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			r.intercept.Add(1)
+			return handler(ctx, req)
+		},
+	}}
+}
+
+// TestServerInterceptors checks panic recovery, default deadlines and that
+// registrar interceptors only see their own service.
+func TestServerInterceptors(t *testing.T) {
+	cfg := config.New()
+	cfg.GRPC.Host, cfg.GRPC.Port = "127.0.0.1", "0"
+	cfg.Auth.Disabled = true
+	cfg.GRPC.Timeout = time.Minute
+	cfg.GRPC.MethodTimeouts = []string{"/test.Service/Deadline=2s"}
+
+	reg := &testRegistrar{deadline: make(chan time.Duration, 1)}
+	srv, err := server.NewGRPCServer(cfg, reg)
+	require.NoError(t, err)
+	go srv.Serve()
+	defer srv.Shutdown(context.Background())
+
+	conn, err := grpc.NewClient(srv.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	call := func(ctx context.Context, method string) error {
+		return conn.Invoke(ctx, "/test.Service/"+method, &emptypb.Empty{}, &emptypb.Empty{})
+	}
+
+	// a panic is an Internal error, and the server keeps serving
+	err = call(context.Background(), "Panic")
+	assert.Equal(t, codes.Internal, status.Code(err))
+	require.NoError(t, call(context.Background(), "Deadline"))
+	remaining := <-reg.deadline
+	assert.True(t, remaining > time.Second && remaining <= 2*time.Second, "got %s", remaining)
+
+	// a client deadline is kept, even when longer than the default
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	require.NoError(t, call(ctx, "Deadline"))
+	assert.Greater(t, <-reg.deadline, 20*time.Second)
+
+	assert.Equal(t, int32(3), reg.intercept.Load())
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), reg.intercept.Load(), "registrar interceptor ran for another service")
+}
+
+func TestParseMethodTimeouts(t *testing.T) {
+	_, err := server.ParseMethodTimeouts(time.Second, []string{"/a.B/C=1s", "/a.B/=0s"})
+	require.NoError(t, err)
+	for _, bad := range []string{"a.B/C=1s", "/a.B/C", "/a.B/C=soon", "/a.B/C=-1s"} {
+		_, err := server.ParseMethodTimeouts(time.Second, []string{bad})
+		assert.Error(t, err, bad)
+	}
+}
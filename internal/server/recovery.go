@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"runtime/debug"
+
+	"google.golang.org/grpc"
+
+	svcErr "github.com/oggyb/muzz-exercise/internal/errors"
+	"github.com/oggyb/muzz-exercise/internal/logger"
+)
+
+// RecoveryUnaryInterceptor turns a panic in a handler (or a later
+// interceptor) into codes.Internal, logging the panic value and stack with
+// the request logger, so one bad call does not take the process down.
+func RecoveryUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				resp, err = nil, recovered(ctx, info.FullMethod, p)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// RecoveryStreamInterceptor is RecoveryUnaryInterceptor for streams.
+func RecoveryStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(ss.Context(), info.FullMethod, p)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recovered(ctx context.Context, method string, p any) error {
+	logger.FromContext(ctx).ErrorContext(ctx, "panic in gRPC handler",
+		"method", method, "panic", p, "stack", string(debug.Stack()))
+	return svcErr.Internal("internal error")
+}
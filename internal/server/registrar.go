@@ -6,3 +6,25 @@ import "google.golang.org/grpc"
 type Registrar interface {
 	Register(s *grpc.Server)
 }
+
+// PublicRegistrar is implemented by registrars whose methods may be called
+// without an access token (e.g. login). Entries are full method names or
+// "/pkg.Service/" for a whole service.
+type PublicRegistrar interface {
+	PublicMethods() []string
+}
+
+// Interceptors are server interceptors contributed by a registrar.
+type Interceptors struct {
+	Unary  []grpc.UnaryServerInterceptor
+	Stream []grpc.StreamServerInterceptor
+}
+
+// InterceptorRegistrar is implemented by registrars needing their own
+// interceptors, e.g. a per-service rate limit. They run after the server's
+// (recovery, deadlines, auth), in the order given, and only for calls to
+// service (e.g. "explore.ExploreService"); an empty service applies them
+// to every call.
+type InterceptorRegistrar interface {
+	Interceptors() (service string, interceptors Interceptors)
+}
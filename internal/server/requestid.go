@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/oggyb/muzz-exercise/internal/auth"
+	"github.com/oggyb/muzz-exercise/internal/logger"
+)
+
+// RequestIDHeader is the metadata key carrying the request ID, both ways.
+const RequestIDHeader = "x-request-id"
+
+// maxRequestIDLen bounds client-supplied IDs, which end up in every log line.
+const maxRequestIDLen = 128
+
+// RequestLogUnaryInterceptor gives every call a request ID and a request
+// logger (see logger.FromContext).
+//
+// Behavior:
+//   - Reuses the caller's x-request-id when it is a sane token (up to 128
+//     printable ASCII characters without spaces); otherwise generates one.
+//   - The ID is sent back in the x-request-id response header, even when
+//     the call fails.
+//   - The logger carries request_id, method, peer (remote address) and,
+//     for mTLS clients, client (see auth.ClientIdentity). The auth
+//     interceptor adds caller once the token is verified.
+func RequestLogUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, id := withRequestLogger(ctx, info.FullMethod)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, id))
+		return handler(ctx, req)
+	}
+}
+
+// RequestLogStreamInterceptor is RequestLogUnaryInterceptor for streams.
+func RequestLogStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, id := withRequestLogger(ss.Context(), info.FullMethod)
+		_ = ss.SetHeader(metadata.Pairs(RequestIDHeader, id))
+		return handler(srv, &requestStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func withRequestLogger(ctx context.Context, method string) (context.Context, string) {
+	id := incomingRequestID(ctx)
+	if id == "" {
+		id = newRequestID()
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("request.id", id))
+
+	args := []any{"request_id", id, "method", method}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		args = append(args, "peer", p.Addr.String())
+	}
+	if client, ok := auth.ClientIdentity(ctx); ok {
+		args = append(args, "client", client)
+	}
+	ctx = logger.WithRequestID(ctx, id)
+	return logger.WithContext(ctx, args...), id
+}
+
+func incomingRequestID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(RequestIDHeader)
+	if len(values) == 0 {
+		return ""
+	}
+	id := values[0]
+	if len(id) > maxRequestIDLen {
+		return ""
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return ""
+		}
+	}
+	return id
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// requestStream overrides the context of a server stream.
+type requestStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *requestStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,78 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/oggyb/muzz-exercise/internal/logger"
+	"github.com/oggyb/muzz-exercise/internal/server"
+)
+
+// headerStream records the headers set by an interceptor.
+type headerStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return "/test.Service/Call" }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+// TestRequestLogger checks that the request ID is propagated or generated,
+// echoed in the response header and logged with the method and peer.
+func TestRequestLogger(t *testing.T) {
+	for name, tc := range map[string]struct {
+		incoming string
+		want     string
+	}{
+		"propagated": {incoming: "abc-123", want: "abc-123"},
+		"missing":    {},
+		"invalid":    {incoming: "has spaces\n"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			ctx := logger.NewContext(context.Background(), slog.New(slog.NewJSONHandler(&buf, nil)))
+			ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 7), Port: 5000}})
+			if tc.incoming != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(server.RequestIDHeader, tc.incoming))
+			}
+			stream := &headerStream{}
+			ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+			var seen string
+			handler := func(ctx context.Context, _ any) (any, error) {
+				seen, _ = logger.RequestID(ctx)
+				logger.FromContext(ctx).InfoContext(ctx, "handled")
+				return nil, nil
+			}
+			_, err := server.RequestLogUnaryInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}, handler)
+			require.NoError(t, err)
+
+			if tc.want != "" {
+				assert.Equal(t, tc.want, seen)
+			} else {
+				assert.Regexp(t, `^[0-9a-f]{32}$`, seen)
+			}
+			assert.Equal(t, []string{seen}, stream.header.Get(server.RequestIDHeader))
+
+			var line map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+			assert.Equal(t, seen, line["request_id"])
+			assert.Equal(t, "/test.Service/Call", line["method"])
+			assert.Equal(t, "10.0.0.7:5000", line["peer"])
+		})
+	}
+}
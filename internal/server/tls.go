@@ -0,0 +1,202 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/oggyb/muzz-exercise/internal/config"
+	"github.com/oggyb/muzz-exercise/internal/logger"
+)
+
+// CertReloader serves the gRPC server's TLS certificate and client CA pool
+// and swaps them when the files on disk change, so certificates can be
+// rotated without a restart.
+//
+// Behavior:
+//   - Files are re-read every interval and on SIGHUP (see Watch); they are
+//     only re-parsed when their contents changed.
+//   - A broken update (half-written file, key not matching the cert) is
+//     logged and the previous certificate stays in use.
+//   - New settings apply to new connections; established ones keep theirs.
+type CertReloader struct {
+	certFile, keyFile, caFile string
+	clientAuth                tls.ClientAuthType
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	sources [][]byte // cert, key and CA file contents last loaded
+}
+
+// NewCertReloader loads cfg.GRPC.TLS. It returns nil, nil when no
+// certificate is configured (plaintext server).
+func NewCertReloader(cfg *config.Config) (*CertReloader, error) {
+	t := cfg.GRPC.TLS
+	if t.CertFile == "" && t.KeyFile == "" {
+		if t.ClientCAFile != "" {
+			return nil, errors.New("tls: GRPC_TLS_CLIENT_CA_FILE needs GRPC_TLS_CERT_FILE and GRPC_TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+	if t.CertFile == "" || t.KeyFile == "" {
+		return nil, errors.New("tls: GRPC_TLS_CERT_FILE and GRPC_TLS_KEY_FILE must be set together")
+	}
+
+	clientAuth, err := parseClientAuth(t.ClientAuth, t.ClientCAFile != "")
+	if err != nil {
+		return nil, err
+	}
+	r := &CertReloader{
+		certFile:   t.CertFile,
+		keyFile:    t.KeyFile,
+		caFile:     t.ClientCAFile,
+		clientAuth: clientAuth,
+	}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// parseClientAuth maps GRPC_TLS_CLIENT_AUTH to a tls.ClientAuthType.
+// The default is "require" with a client CA and "none" without one.
+func parseClientAuth(mode string, haveCA bool) (tls.ClientAuthType, error) {
+	switch strings.ToLower(mode) {
+	case "":
+		if haveCA {
+			return tls.RequireAndVerifyClientCert, nil
+		}
+		return tls.NoClientCert, nil
+	case "none":
+		return tls.NoClientCert, nil
+	case "request":
+		if !haveCA {
+			return 0, errors.New("tls: client auth \"request\" needs GRPC_TLS_CLIENT_CA_FILE")
+		}
+		return tls.VerifyClientCertIfGiven, nil
+	case "require":
+		if !haveCA {
+			return 0, errors.New("tls: client auth \"require\" needs GRPC_TLS_CLIENT_CA_FILE")
+		}
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return 0, fmt.Errorf("tls: unknown client auth %q (want none, request or require)", mode)
+}
+
+// Reload re-reads the files and swaps in the new certificate and CA pool.
+// It reports whether anything changed; on error the old ones are kept.
+func (r *CertReloader) Reload() (bool, error) {
+	files := []string{r.certFile, r.keyFile}
+	if r.caFile != "" {
+		files = append(files, r.caFile)
+	}
+	sources := make([][]byte, len(files))
+	for i, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return false, fmt.Errorf("tls: read %s: %w", f, err)
+		}
+		sources[i] = b
+	}
+
+	r.mu.RLock()
+	unchanged := sameSources(r.sources, sources)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(sources[0], sources[1])
+	if err != nil {
+		return false, fmt.Errorf("tls: load key pair: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(sources[2]) {
+			return false, fmt.Errorf("tls: no certificates in %s", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.pool, r.sources = &cert, pool, sources
+	r.mu.Unlock()
+	return true, nil
+}
+
+func sameSources(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// TLSConfig returns a server config that picks up the current certificate
+// and client CA pool on every handshake.
+func (r *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    r.pool,
+				NextProtos:   []string{"h2"},
+			}, nil
+		},
+	}
+}
+
+// Watch reloads the files every interval and on SIGHUP until ctx is done.
+// An interval <= 0 leaves only SIGHUP.
+//
+// Example:
+//
+//	go reloader.Watch(ctx, time.Minute)
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	log := logger.L()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-hup:
+		}
+		changed, err := r.Reload()
+		switch {
+		case err != nil:
+			log.Error("tls reload failed, keeping current certificate", "err", err)
+		case changed:
+			log.Info("tls certificate reloaded", "cert", r.certFile)
+		}
+	}
+}
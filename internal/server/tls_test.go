@@ -0,0 +1,165 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/oggyb/muzz-exercise/internal/auth"
+	"github.com/oggyb/muzz-exercise/internal/config"
+	"github.com/oggyb/muzz-exercise/internal/server"
+)
+
+// testCA is a self-signed CA issuing leaf certificates for the tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns PEM cert and key for a leaf with the given serial, usage and SANs.
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage, tmpl x509.Certificate) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl.SerialNumber = big.NewInt(serial)
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	tmpl.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+// TestMutualTLSAndReload serves gRPC health checks over mTLS, checks the
+// client identity comes from the certificate SAN, that clients without a
+// certificate are refused, and that a rotated server certificate is served
+// to new connections after Reload.
+func TestMutualTLSAndReload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	serverTmpl := x509.Certificate{IPAddresses: []net.IP{net.ParseIP("127.0.0.1")}}
+	certPEM, keyPEM := ca.issue(t, 10, x509.ExtKeyUsageServerAuth, serverTmpl)
+
+	cfg := config.New()
+	cfg.GRPC.TLS.CertFile = filepath.Join(dir, "server.crt")
+	cfg.GRPC.TLS.KeyFile = filepath.Join(dir, "server.key")
+	cfg.GRPC.TLS.ClientCAFile = filepath.Join(dir, "ca.crt")
+	writeFile(t, cfg.GRPC.TLS.CertFile, certPEM)
+	writeFile(t, cfg.GRPC.TLS.KeyFile, keyPEM)
+	writeFile(t, cfg.GRPC.TLS.ClientCAFile, ca.pem)
+
+	reloader, err := server.NewCertReloader(cfg)
+	require.NoError(t, err)
+
+	identities := make(chan string, 1)
+	srv := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(reloader.TLSConfig())),
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+			id, _ := auth.ClientIdentity(ctx)
+			identities <- id
+			return h(ctx, req)
+		}),
+	)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	spiffe, _ := url.Parse("spiffe://test/ns/default/sa/client")
+	clientCert, clientKey := ca.issue(t, 20, x509.ExtKeyUsageClientAuth, x509.Certificate{URIs: []*url.URL{spiffe}})
+	pair, err := tls.X509KeyPair(clientCert, clientKey)
+	require.NoError(t, err)
+
+	check := func(certs ...tls.Certificate) error {
+		conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(
+			credentials.NewTLS(&tls.Config{RootCAs: roots, Certificates: certs})))
+		require.NoError(t, err)
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		return err
+	}
+
+	require.NoError(t, check(pair))
+	assert.Equal(t, spiffe.String(), <-identities)
+	assert.Error(t, check(), "client without a certificate must be refused")
+
+	servedSerial := func() int64 {
+		c, err := tls.Dial("tcp", lis.Addr().String(), &tls.Config{
+			RootCAs: roots, Certificates: []tls.Certificate{pair}, NextProtos: []string{"h2"},
+		})
+		require.NoError(t, err)
+		defer c.Close()
+		return c.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+	}
+	assert.Equal(t, int64(10), servedSerial())
+
+	// a broken update keeps the current certificate
+	writeFile(t, cfg.GRPC.TLS.CertFile, []byte("not a certificate"))
+	_, err = reloader.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, int64(10), servedSerial())
+
+	certPEM, keyPEM = ca.issue(t, 11, x509.ExtKeyUsageServerAuth, serverTmpl)
+	writeFile(t, cfg.GRPC.TLS.CertFile, certPEM)
+	writeFile(t, cfg.GRPC.TLS.KeyFile, keyPEM)
+	changed, err := reloader.Reload()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, int64(11), servedSerial())
+	require.NoError(t, check(pair))
+	<-identities
+}
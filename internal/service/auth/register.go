@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"google.golang.org/grpc"
+
+	"github.com/oggyb/muzz-exercise/internal/app"
+	pb "github.com/oggyb/muzz-exercise/internal/proto/auth"
+	"github.com/oggyb/muzz-exercise/internal/server"
+)
+
+// Registrar ties the Auth service into the gRPC server
+type Registrar struct {
+	appCtx *app.AppContext
+}
+
+// NewRegistrar creates a new Registrar for the Auth service
+func NewRegistrar(appCtx *app.AppContext) *Registrar {
+	return &Registrar{appCtx: appCtx}
+}
+
+// Register attaches the Auth service implementation to the gRPC server
+func (r *Registrar) Register(s *grpc.Server) {
+	service := NewAuthService(r.appCtx)
+	pb.RegisterAuthServiceServer(s, service)
+}
+
+// PublicMethods lists the Auth methods callable without an access token:
+// all of them, since they are how a client obtains one.
+func (r *Registrar) PublicMethods() []string {
+	return []string{"/" + pb.AuthService_ServiceDesc.ServiceName + "/"}
+}
+
+// HealthProbes makes AuthService depend on the database and on Redis,
+// which holds the refresh sessions.
+func (r *Registrar) HealthProbes() (string, []server.Probe) {
+	return pb.AuthService_ServiceDesc.ServiceName, []server.Probe{r.appCtx.DatabaseProbe(), r.appCtx.RedisProbe()}
+}
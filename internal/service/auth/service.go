@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/oggyb/muzz-exercise/internal/app"
+	jwtauth "github.com/oggyb/muzz-exercise/internal/auth"
+	"github.com/oggyb/muzz-exercise/internal/db"
+	svcErr "github.com/oggyb/muzz-exercise/internal/errors"
+	"github.com/oggyb/muzz-exercise/internal/logger"
+	pb "github.com/oggyb/muzz-exercise/internal/proto/auth"
+	"github.com/oggyb/muzz-exercise/internal/repository"
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9_.]{3,32}$`)
+
+// Service implements the Auth gRPC API: account registration and the
+// access/refresh token lifecycle. Access tokens are JWTs checked by the
+// server's auth interceptor; refresh tokens are opaque and only valid while
+// their session exists in Redis.
+type Service struct {
+	appCtx *app.AppContext
+	users  *repository.UserRepository
+	issuer *jwtauth.Issuer
+
+	pb.UnimplementedAuthServiceServer
+}
+
+// NewAuthService creates a new Auth service with dependencies from AppContext.
+// Dependencies include:
+//   - DB connection for users (via UserRepository)
+//   - RedisCache for refresh sessions
+//   - Issuer for signing access tokens
+func NewAuthService(appCtx *app.AppContext) *Service {
+	return &Service{
+		appCtx: appCtx,
+		users:  repository.NewUserRepository(appCtx.DB),
+		issuer: appCtx.Issuer,
+	}
+}
+
+// Register creates a user account.
+//
+// Behavior:
+//   - Username is lowercased and must match [a-z0-9_.]{3,32}.
+//   - Email is trimmed, lowercased and must be a plain address.
+//   - Password must be 8 to 72 bytes (bcrypt's limit) and is stored as a
+//     bcrypt hash.
+//   - A taken username or email fails with AlreadyExists.
+//
+// Example:
+//
+//	svc.Register(ctx, &pb.RegisterRequest{Username: "alice", Email: "a@x.io", Password: "…", Gender: "female"})
+func (s *Service) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
+	username := strings.ToLower(strings.TrimSpace(req.GetUsername()))
+	if !usernamePattern.MatchString(username) {
+		return nil, svcErr.InvalidField("username", "must be 3-32 characters of a-z, 0-9, '_' or '.'")
+	}
+	email, err := normalizeEmail(req.GetEmail())
+	if err != nil {
+		return nil, err
+	}
+	if n := len(req.GetPassword()); n < 8 || n > 72 {
+		return nil, svcErr.InvalidField("password", "must be 8 to 72 bytes")
+	}
+	gender := strings.ToLower(strings.TrimSpace(req.GetGender()))
+	if gender == "" || len(gender) > 16 {
+		return nil, svcErr.InvalidField("gender", "must be 1 to 16 characters")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.GetPassword()), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, svcErr.Map(err)
+	}
+
+	user := &db.User{
+		Username:     username,
+		Email:        email,
+		PasswordHash: string(hash),
+		Active:       true,
+		Gender:       gender,
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrUsernameTaken) {
+			return nil, svcErr.AlreadyExists(svcErr.ReasonUsernameTaken, err.Error())
+		}
+		if errors.Is(err, repository.ErrEmailTaken) {
+			return nil, svcErr.AlreadyExists(svcErr.ReasonEmailTaken, err.Error())
+		}
+		return nil, svcErr.Map(err)
+	}
+
+	logger.FromContext(ctx).InfoContext(ctx, "user registered", "user_id", user.ID)
+	return &pb.RegisterResponse{UserId: strconv.FormatUint(user.ID, 10)}, nil
+}
+
+// Login verifies a username or email and password and issues tokens.
+//
+// Behavior:
+//   - Unknown users and wrong passwords both fail with Unauthenticated
+//     and take the same bcrypt time, so accounts cannot be enumerated.
+//   - Inactive users fail with PermissionDenied.
+//   - Updates LastLoginAt and opens a refresh session in Redis.
+//
+// Example:
+//
+//	svc.Login(ctx, &pb.LoginRequest{Login: "alice", Password: "…"})
+func (s *Service) Login(ctx context.Context, req *pb.LoginRequest) (*pb.TokenResponse, error) {
+	login := strings.ToLower(strings.TrimSpace(req.GetLogin()))
+
+	user, err := s.users.FindByLogin(ctx, login)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, svcErr.Map(err)
+	}
+	hash := dummyHash()
+	if user != nil {
+		hash = []byte(user.PasswordHash)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(req.GetPassword())) != nil || user == nil {
+		return nil, svcErr.Unauthenticated(svcErr.ReasonInvalidCredentials, "invalid credentials")
+	}
+	if !user.Active {
+		return nil, svcErr.PermissionDenied(svcErr.ReasonAccountDisabled, "account is disabled")
+	}
+
+	if err := s.users.TouchLastLogin(ctx, user.ID, time.Now()); err != nil {
+		return nil, svcErr.Map(err)
+	}
+	return s.issue(ctx, user.ID)
+}
+
+// RefreshToken exchanges a refresh token for a new access and refresh token.
+//
+// Behavior:
+//   - Refresh tokens are single use: the session is deleted atomically
+//     (GETDEL), so a replayed token fails with Unauthenticated.
+//   - Users deleted or disabled since login cannot refresh.
+//
+// Example:
+//
+//	svc.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: "…"})
+func (s *Service) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.TokenResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, svcErr.InvalidField("refresh_token", "is required")
+	}
+
+	key := s.appCtx.RedisCache.KeyForRefreshSession(jwtauth.RefreshTokenHash(req.GetRefreshToken()))
+	val, err := s.appCtx.RedisCache.GetDel(ctx, key)
+	if errors.Is(err, redis.Nil) {
+		return nil, svcErr.Unauthenticated(svcErr.ReasonRefreshInvalid, "refresh token is invalid or expired")
+	}
+	if err != nil {
+		return nil, svcErr.Unavailable(fmt.Errorf("refresh session lookup: %w", err), "session store unavailable")
+	}
+	userID, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		return nil, svcErr.Unauthenticated(svcErr.ReasonRefreshInvalid, "refresh token is invalid or expired")
+	}
+
+	user, err := s.users.FindByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, svcErr.Unauthenticated(svcErr.ReasonRefreshInvalid, "refresh token is invalid or expired")
+	}
+	if err != nil {
+		return nil, svcErr.Map(err)
+	}
+	if !user.Active {
+		return nil, svcErr.PermissionDenied(svcErr.ReasonAccountDisabled, "account is disabled")
+	}
+	return s.issue(ctx, user.ID)
+}
+
+// Logout revokes a refresh token. Access tokens stay valid until they
+// expire, so keep AUTH_ACCESS_TTL short.
+//
+// Example:
+//
+//	svc.Logout(ctx, &pb.LogoutRequest{RefreshToken: "…"})
+func (s *Service) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.LogoutResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, svcErr.InvalidField("refresh_token", "is required")
+	}
+	key := s.appCtx.RedisCache.KeyForRefreshSession(jwtauth.RefreshTokenHash(req.GetRefreshToken()))
+	if err := s.appCtx.RedisCache.Del(ctx, key); err != nil {
+		return nil, svcErr.Unavailable(fmt.Errorf("refresh session delete: %w", err), "session store unavailable")
+	}
+	return &pb.LogoutResponse{}, nil
+}
+
+// issue signs an access token and opens a refresh session for userID.
+func (s *Service) issue(ctx context.Context, userID uint64) (*pb.TokenResponse, error) {
+	access, accessExp, err := s.issuer.Issue(userID)
+	if err != nil {
+		return nil, svcErr.Map(err)
+	}
+	refresh, hash, refreshExp, err := s.issuer.NewRefreshToken()
+	if err != nil {
+		return nil, svcErr.Map(err)
+	}
+
+	key := s.appCtx.RedisCache.KeyForRefreshSession(hash)
+	id := strconv.FormatUint(userID, 10)
+	if err := s.appCtx.RedisCache.Set(ctx, key, id, s.issuer.RefreshTTL()); err != nil {
+		return nil, svcErr.Unavailable(fmt.Errorf("refresh session store: %w", err), "session store unavailable")
+	}
+
+	return &pb.TokenResponse{
+		UserId:           id,
+		AccessToken:      access,
+		AccessExpiresAt:  uint64(accessExp.UnixMilli()),
+		RefreshToken:     refresh,
+		RefreshExpiresAt: uint64(refreshExp.UnixMilli()),
+	}, nil
+}
+
+// normalizeEmail trims and lowercases an email and rejects anything but a
+// bare address (no display name) that fits users.email.
+func normalizeEmail(raw string) (string, error) {
+	email := strings.ToLower(strings.TrimSpace(raw))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 128 {
+		return "", svcErr.InvalidField("email", "must be a valid address")
+	}
+	return email, nil
+}
+
+var (
+	dummyOnce sync.Once
+	dummy     []byte
+)
+
+// dummyHash is compared against when the user does not exist, so a failed
+// login costs the same whether or not the account exists.
+func dummyHash() []byte {
+	dummyOnce.Do(func() {
+		dummy, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	})
+	return dummy
+}
@@ -0,0 +1,133 @@
+package auth_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/oggyb/muzz-exercise/internal/app"
+	jwtauth "github.com/oggyb/muzz-exercise/internal/auth"
+	"github.com/oggyb/muzz-exercise/internal/cache"
+	"github.com/oggyb/muzz-exercise/internal/config"
+	"github.com/oggyb/muzz-exercise/internal/db"
+	pb "github.com/oggyb/muzz-exercise/internal/proto/auth"
+	"github.com/oggyb/muzz-exercise/internal/service/auth"
+)
+
+// setupService wires an Auth service to an in-memory SQLite DB, a
+// miniredis and an HS256 issuer, and returns a verifier for the same key.
+func setupService(t *testing.T) (*auth.Service, *jwtauth.Verifier) {
+	t.Helper()
+
+	dbName := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	dbase, err := gorm.Open(sqlite.Open(dbName), &gorm.Config{
+		NowFunc: func() time.Time { return time.Now().UTC().Truncate(time.Millisecond) },
+	})
+	require.NoError(t, err)
+	sqlDB, err := dbase.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	require.NoError(t, dbase.AutoMigrate(&db.User{}))
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(func() { mr.Close() })
+
+	cfg := config.New()
+	cfg.Redis.Addr = mr.Addr()
+	cfg.Auth.JWTSecret = "test-secret-test-secret-test-secret"
+
+	appCtx := app.New(dbase, cache.NewRedisCache(cfg), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	appCtx.Issuer, err = jwtauth.NewIssuer(cfg)
+	require.NoError(t, err)
+	verifier, err := jwtauth.NewVerifier(cfg)
+	require.NoError(t, err)
+
+	return auth.NewAuthService(appCtx), verifier
+}
+
+func requireCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	st, ok := status.FromError(err)
+	require.True(t, ok, "expected gRPC status, got %v", err)
+	assert.Equal(t, code, st.Code())
+}
+
+// TestRegisterAndLogin registers a user, rejects duplicates and bad
+// passwords, and checks the issued access token verifies.
+func TestRegisterAndLogin(t *testing.T) {
+	ctx := context.Background()
+	svc, verifier := setupService(t)
+
+	reg, err := svc.Register(ctx, &pb.RegisterRequest{
+		Username: "Alice", Email: "Alice@Example.com", Password: "correct horse", Gender: "female",
+	})
+	require.NoError(t, err)
+
+	_, err = svc.Register(ctx, &pb.RegisterRequest{
+		Username: "alice", Email: "other@example.com", Password: "correct horse", Gender: "female",
+	})
+	requireCode(t, err, codes.AlreadyExists)
+	_, err = svc.Register(ctx, &pb.RegisterRequest{
+		Username: "bob", Email: "alice@example.com", Password: "correct horse", Gender: "male",
+	})
+	requireCode(t, err, codes.AlreadyExists)
+	_, err = svc.Register(ctx, &pb.RegisterRequest{
+		Username: "bob", Email: "bob@example.com", Password: "short", Gender: "male",
+	})
+	requireCode(t, err, codes.InvalidArgument)
+
+	_, err = svc.Login(ctx, &pb.LoginRequest{Login: "alice", Password: "wrong password"})
+	requireCode(t, err, codes.Unauthenticated)
+	_, err = svc.Login(ctx, &pb.LoginRequest{Login: "nobody", Password: "correct horse"})
+	requireCode(t, err, codes.Unauthenticated)
+
+	tokens, err := svc.Login(ctx, &pb.LoginRequest{Login: "ALICE@example.com", Password: "correct horse"})
+	require.NoError(t, err)
+	assert.Equal(t, reg.UserId, tokens.UserId)
+
+	userID, err := verifier.Verify(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, reg.UserId, fmt.Sprint(userID))
+}
+
+// TestRefreshRotationAndLogout checks refresh tokens are single use and
+// revoked by Logout.
+func TestRefreshRotationAndLogout(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := setupService(t)
+
+	_, err := svc.Register(ctx, &pb.RegisterRequest{
+		Username: "carol", Email: "carol@example.com", Password: "correct horse", Gender: "female",
+	})
+	require.NoError(t, err)
+	first, err := svc.Login(ctx, &pb.LoginRequest{Login: "carol", Password: "correct horse"})
+	require.NoError(t, err)
+
+	second, err := svc.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	// replaying the rotated token fails
+	_, err = svc.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	requireCode(t, err, codes.Unauthenticated)
+
+	_, err = svc.Logout(ctx, &pb.LogoutRequest{RefreshToken: second.RefreshToken})
+	require.NoError(t, err)
+	_, err = svc.Logout(ctx, &pb.LogoutRequest{RefreshToken: second.RefreshToken})
+	require.NoError(t, err)
+
+	_, err = svc.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: second.RefreshToken})
+	requireCode(t, err, codes.Unauthenticated)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/oggyb/muzz-exercise/internal/app"
 	pb "github.com/oggyb/muzz-exercise/internal/proto/explore"
+	"github.com/oggyb/muzz-exercise/internal/server"
 )
 
 // Registrar ties the Explore service into the gRPC server
@@ -22,3 +23,9 @@ func (r *Registrar) Register(s *grpc.Server) {
 	service := NewExploreService(r.appCtx)
 	pb.RegisterExploreServiceServer(s, service)
 }
+
+// HealthProbes makes ExploreService depend on the databases only: without
+// Redis it still answers, counting from the database.
+func (r *Registrar) HealthProbes() (string, []server.Probe) {
+	return pb.ExploreService_ServiceDesc.ServiceName, []server.Probe{r.appCtx.DatabaseProbe()}
+}
@@ -2,33 +2,69 @@ package explore
 
 import (
 	"context"
+	"errors"
 	"strconv"
 	"time"
 
+	"github.com/redis/go-redis/v9"
+
 	"github.com/oggyb/muzz-exercise/internal/app"
+	"github.com/oggyb/muzz-exercise/internal/auth"
+	"github.com/oggyb/muzz-exercise/internal/db"
 	svcErr "github.com/oggyb/muzz-exercise/internal/errors"
+	"github.com/oggyb/muzz-exercise/internal/logger"
+	"github.com/oggyb/muzz-exercise/internal/metrics"
 	pb "github.com/oggyb/muzz-exercise/internal/proto/explore"
 	"github.com/oggyb/muzz-exercise/internal/repository"
 )
 
+// likeCountCache labels the like count cache in cache_requests_total.
+const likeCountCache = "like_count"
+
+// DefaultPageSize is the ListLikedYou page size when AppContext.PageSize
+// is not set.
+const DefaultPageSize = 5
+
 // Service implements the Explore gRPC API.
 // It contains the business logic on top of repository and cache layers.
 // Each method corresponds to a gRPC endpoint defined in explore.proto.
+//
+// Callers may only act as themselves: the authenticated user must be the
+// recipient for list/count calls and the actor for PutDecision
+// (PermissionDenied otherwise).
 type Service struct {
 	appCtx       *app.AppContext
 	decisionRepo *repository.DecisionRepository
+	pageSize     int
 
 	pb.UnimplementedExploreServiceServer
 }
 
 // NewExploreService creates a new Explore service with dependencies from AppContext.
 // Dependencies include:
-//   - DB connection (via DecisionRepository)
+//   - DB connection or decision shards (via DecisionRepository)
+//   - Pagination token codec, retry policy and page size, if configured
 //   - RedisCache for counters from AppContext
 func NewExploreService(appCtx *app.AppContext) *Service {
+	shards := appCtx.Shards
+	if shards == nil {
+		shards = repository.NewShardRouter(appCtx.DB)
+	}
+	repo := repository.NewShardedDecisionRepository(shards)
+	if appCtx.Tokens != nil {
+		repo.WithTokens(appCtx.Tokens)
+	}
+	if appCtx.Retry != nil {
+		repo.WithRetry(*appCtx.Retry)
+	}
+	pageSize := appCtx.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
 	return &Service{
 		appCtx:       appCtx,
-		decisionRepo: repository.NewDecisionRepository(appCtx.DB),
+		decisionRepo: repo,
+		pageSize:     pageSize,
 	}
 }
 
@@ -37,7 +73,11 @@ func NewExploreService(appCtx *app.AppContext) *Service {
 // Behavior:
 //   - Fetches likes for the given recipient via repository.GetLikers.
 //   - Excludes users that the recipient explicitly passed.
-//   - Supports cursor-based pagination with paginationToken.
+//   - Supports cursor-based pagination with paginationToken in both
+//     directions: next_pagination_token for older likes,
+//     prev_pagination_token for likes newer than the page's first one.
+//   - Optional since/until (unix millis) limit the time window, e.g. to
+//     fetch only likes received since the last visit.
 //   - Returns actor_id + timestamp pairs.
 //
 // Example:
@@ -45,32 +85,30 @@ func NewExploreService(appCtx *app.AppContext) *Service {
 //	svc.ListLikedYou(ctx, &pb.ListLikedYouRequest{RecipientUserId: "42"})
 func (s *Service) ListLikedYou(ctx context.Context, req *pb.ListLikedYouRequest) (*pb.ListLikedYouResponse, error) {
 
-	s.appCtx.Logger.Debug("ListLikedYou called", "recipient", req.GetRecipientUserId(), "token", req.GetPaginationToken())
+	logger.FromContext(ctx).DebugContext(ctx, "ListLikedYou called", "recipient", req.GetRecipientUserId(), "token", req.GetPaginationToken())
 
 	recipientID, err := strconv.ParseUint(req.GetRecipientUserId(), 10, 64)
 	if err != nil {
-		s.appCtx.Logger.Error("Invalid recipient_user_id", "value", req.GetRecipientUserId(), "err", err)
-		return nil, svcErr.InvalidArgument("recipient_user_id must be a valid uint64")
+		logger.FromContext(ctx).ErrorContext(ctx, "Invalid recipient_user_id", "value", req.GetRecipientUserId(), "err", err)
+		return nil, svcErr.InvalidField("recipient_user_id", "must be a valid uint64")
+	}
+	if err := auth.RequireCaller(ctx, recipientID); err != nil {
+		return nil, err
 	}
 
-	decisions, nextToken, err := s.decisionRepo.GetLikers(ctx, recipientID, req.PaginationToken, 5)
+	page, err := pageFromRequest(req, s.pageSize)
 	if err != nil {
-		s.appCtx.Logger.Error("GetLikers failed", "err", err)
-		return nil, svcErr.Map(err)
+		return nil, err
 	}
 
-	resp := &pb.ListLikedYouResponse{}
-	for _, d := range decisions {
-		resp.Likers = append(resp.Likers, &pb.ListLikedYouResponse_Liker{
-			ActorId:       strconv.FormatUint(d.ActorID, 10),
-			UnixTimestamp: uint64(d.UpdatedAt.UnixMilli()),
-		})
-	}
-	if nextToken != nil {
-		resp.NextPaginationToken = nextToken
+	decisions, tokens, err := s.decisionRepo.GetLikers(ctx, recipientID, page)
+	if err != nil {
+		return nil, svcErr.Map(err)
 	}
 
-	s.appCtx.Logger.Debug("ListLikedYou result", "liker_count", len(resp.Likers), "next_token", resp.GetNextPaginationToken())
+	resp := likersResponse(decisions, tokens)
+
+	logger.FromContext(ctx).DebugContext(ctx, "ListLikedYou result", "liker_count", len(resp.Likers), "next_token", resp.GetNextPaginationToken())
 
 	return resp, nil
 }
@@ -81,75 +119,125 @@ func (s *Service) ListLikedYou(ctx context.Context, req *pb.ListLikedYouRequest)
 //   - Uses repository.GetNewLikers to exclude mutual likes.
 //   - Excludes users the recipient explicitly passed.
 //   - Returns actor_id + timestamp pairs.
-//   - Supports cursor-based pagination.
+//   - Paginates and filters like ListLikedYou.
 //
 // Example:
 //
 //	svc.ListNewLikedYou(ctx, &pb.ListLikedYouRequest{RecipientUserId: "42"})
 func (s *Service) ListNewLikedYou(ctx context.Context, req *pb.ListLikedYouRequest) (*pb.ListLikedYouResponse, error) {
-	s.appCtx.Logger.Debug("ListNewLikedYou called", "recipient", req.GetRecipientUserId())
+	logger.FromContext(ctx).DebugContext(ctx, "ListNewLikedYou called", "recipient", req.GetRecipientUserId())
 
 	recipientID, err := strconv.ParseUint(req.GetRecipientUserId(), 10, 64)
 	if err != nil {
-		return nil, svcErr.InvalidArgument("recipient_user_id must be a valid uint64")
+		return nil, svcErr.InvalidField("recipient_user_id", "must be a valid uint64")
+	}
+	if err := auth.RequireCaller(ctx, recipientID); err != nil {
+		return nil, err
 	}
 
-	decisions, nextToken, err := s.decisionRepo.GetNewLikers(ctx, recipientID, req.PaginationToken, 5)
+	page, err := pageFromRequest(req, s.pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	decisions, tokens, err := s.decisionRepo.GetNewLikers(ctx, recipientID, page)
 	if err != nil {
 		return nil, svcErr.Map(err)
 	}
 
-	resp := &pb.ListLikedYouResponse{}
+	return likersResponse(decisions, tokens), nil
+}
+
+// pageFromRequest reads the pagination token and the optional since/until
+// window (unix millis) shared by both list endpoints.
+func pageFromRequest(req *pb.ListLikedYouRequest, limit int) (repository.Page, error) {
+	page := repository.Page{Token: req.PaginationToken, Limit: limit}
+	if req.SinceUnixTimestamp != nil {
+		since := time.UnixMilli(int64(req.GetSinceUnixTimestamp()))
+		page.Since = &since
+	}
+	if req.UntilUnixTimestamp != nil {
+		until := time.UnixMilli(int64(req.GetUntilUnixTimestamp()))
+		page.Until = &until
+	}
+	if page.Since != nil && page.Until != nil && !page.Since.Before(*page.Until) {
+		return page, svcErr.InvalidField("since_unix_timestamp", "must be before until_unix_timestamp")
+	}
+	return page, nil
+}
+
+// likersResponse converts a page of decisions into the list response.
+func likersResponse(decisions []db.Decision, tokens repository.PageTokens) *pb.ListLikedYouResponse {
+	resp := &pb.ListLikedYouResponse{
+		NextPaginationToken: tokens.Next,
+		PrevPaginationToken: tokens.Prev,
+	}
 	for _, d := range decisions {
 		resp.Likers = append(resp.Likers, &pb.ListLikedYouResponse_Liker{
 			ActorId:       strconv.FormatUint(d.ActorID, 10),
 			UnixTimestamp: uint64(d.UpdatedAt.UnixMilli()),
 		})
 	}
-	if nextToken != nil {
-		resp.NextPaginationToken = nextToken
-	}
-
-	return resp, nil
+	return resp
 }
 
 // CountLikedYou returns how many users liked the recipient.
 // Cache-first strategy:
-//  1. Attempts to read from Redis (likes:count:userID).
-//  2. If cache miss or parse error, falls back to DB via repository.CountLikers.
-//  3. On DB fetch, updates Redis with a 1h TTL.
+//  1. Attempts to read from Redis (likes:count:userID by default).
+//  2. If cache miss, parse error or Redis is unavailable (circuit breaker open),
+//     reads the user's user_stats row (one primary-key lookup), and only
+//     counts via repository.CountLikers when the user has no stats yet.
+//  3. On DB fetch, updates Redis with cfg.Cache.LikeCountTTL (1h by default).
 //
 // Example:
 //
 //	svc.CountLikedYou(ctx, &pb.CountLikedYouRequest{RecipientUserId: "42"})
 func (s *Service) CountLikedYou(ctx context.Context, req *pb.CountLikedYouRequest) (*pb.CountLikedYouResponse, error) {
-	s.appCtx.Logger.Debug("CountLikedYou called", "recipient", req.GetRecipientUserId())
+	logger.FromContext(ctx).DebugContext(ctx, "CountLikedYou called", "recipient", req.GetRecipientUserId())
 
 	// parse recipient ID
 	recipientID, err := strconv.ParseUint(req.GetRecipientUserId(), 10, 64)
 	if err != nil {
-		return nil, svcErr.InvalidArgument("recipient_user_id must be a valid uint64")
+		return nil, svcErr.InvalidField("recipient_user_id", "must be a valid uint64")
+	}
+	if err := auth.RequireCaller(ctx, recipientID); err != nil {
+		return nil, err
 	}
 
 	key := s.appCtx.RedisCache.KeyForLikeCount(recipientID)
 
-	// try cache first
-	if cached, _ := s.appCtx.RedisCache.Get(ctx, key); cached != "" {
+	// try cache first; errors (including an open circuit breaker) fall through to the DB
+	cached, err := s.appCtx.RedisCache.Get(ctx, key)
+	switch {
+	case errors.Is(err, redis.Nil):
+		metrics.ObserveCache(likeCountCache, metrics.ResultMiss)
+	case err != nil:
+		metrics.ObserveCache(likeCountCache, metrics.ResultError)
+		logger.FromContext(ctx).DebugContext(ctx, "like count cache unavailable, using DB", "recipient", recipientID, "err", err)
+	default:
 		if n, err := strconv.ParseUint(cached, 10, 64); err == nil {
+			metrics.ObserveCache(likeCountCache, metrics.ResultHit)
 			// refresh TTL since this user is active
-			_ = s.appCtx.RedisCache.Client.Expire(ctx, key, time.Hour).Err()
+			_ = s.appCtx.RedisCache.Expire(ctx, key, s.appCtx.RedisCache.LikeCountTTL())
 			return &pb.CountLikedYouResponse{Count: n}, nil
 		}
+		metrics.ObserveCache(likeCountCache, metrics.ResultError)
 	}
 
-	// fallback: DB
-	count, err := s.decisionRepo.CountLikers(ctx, recipientID)
+	// fallback: DB, denormalized counter first
+	stats, err := s.decisionRepo.GetUserStats(ctx, recipientID)
 	if err != nil {
 		return nil, svcErr.Map(err)
 	}
+	var count int64
+	if stats != nil {
+		count = stats.LikedYou
+	} else if count, err = s.decisionRepo.CountLikers(ctx, recipientID); err != nil {
+		return nil, svcErr.Map(err)
+	}
 
 	// set + TTL refresh
-	_ = s.appCtx.RedisCache.Set(ctx, key, strconv.FormatInt(count, 10), time.Hour)
+	_ = s.appCtx.RedisCache.Set(ctx, key, strconv.FormatInt(count, 10), s.appCtx.RedisCache.LikeCountTTL())
 
 	return &pb.CountLikedYouResponse{Count: uint64(count)}, nil
 }
@@ -167,7 +255,7 @@ func (s *Service) CountLikedYou(ctx context.Context, req *pb.CountLikedYouReques
 //
 //	svc.PutDecision(ctx, &pb.PutDecisionRequest{ActorUserId: "1", RecipientUserId: "2", LikedRecipient: true})
 func (s *Service) PutDecision(ctx context.Context, req *pb.PutDecisionRequest) (*pb.PutDecisionResponse, error) {
-	s.appCtx.Logger.Debug(
+	logger.FromContext(ctx).DebugContext(ctx,
 		"PutDecision called",
 		"actor", req.GetActorUserId(),
 		"recipient", req.GetRecipientUserId(),
@@ -175,15 +263,18 @@ func (s *Service) PutDecision(ctx context.Context, req *pb.PutDecisionRequest) (
 	)
 	actorID, err := strconv.ParseUint(req.GetActorUserId(), 10, 64)
 	if err != nil {
-		return nil, svcErr.InvalidArgument("actor_user_id must be a valid uint64")
+		return nil, svcErr.InvalidField("actor_user_id", "must be a valid uint64")
 	}
 	recipientID, err := strconv.ParseUint(req.GetRecipientUserId(), 10, 64)
 	if err != nil {
-		return nil, svcErr.InvalidArgument("recipient_user_id must be a valid uint64")
+		return nil, svcErr.InvalidField("recipient_user_id", "must be a valid uint64")
 	}
 
 	if actorID == recipientID {
-		return nil, svcErr.InvalidArgument("cannot decide on yourself")
+		return nil, svcErr.InvalidField("recipient_user_id", "must differ from actor_user_id")
+	}
+	if err := auth.RequireCaller(ctx, actorID); err != nil {
+		return nil, err
 	}
 
 	prev, err := s.decisionRepo.CreateOrUpdateDecision(ctx, actorID, recipientID, req.GetLikedRecipient())
@@ -191,25 +282,26 @@ func (s *Service) PutDecision(ctx context.Context, req *pb.PutDecisionRequest) (
 		return nil, svcErr.Map(err)
 	}
 
-	// update cache
+	// update cache; failures leave the cached count off until its TTL
+	// expires, so they are counted (like_counter_update_failures_total)
 	key := s.appCtx.RedisCache.KeyForLikeCount(recipientID)
 	// Update Redis counter based on previous vs new value
 	if prev == nil {
 		// First time a decision is made → increment if it's a like
 		if req.GetLikedRecipient() {
-			_, _ = s.appCtx.RedisCache.Incr(ctx, key) // like count +1
+			s.adjustLikeCount(ctx, "incr", key) // like count +1
 		}
 	} else if *prev != req.GetLikedRecipient() {
 		// Decision changed → adjust counter accordingly
 		if req.GetLikedRecipient() {
 			// Previously was "unlike", now changed to "like"
-			_, _ = s.appCtx.RedisCache.Incr(ctx, key) // like count +1
+			s.adjustLikeCount(ctx, "incr", key) // like count +1
 		} else {
 			// Previously was "like", now changed to "unlike"
-			_, _ = s.appCtx.RedisCache.Decr(ctx, key) // like count -1
+			s.adjustLikeCount(ctx, "decr", key) // like count -1
 		}
 	}
-	_ = s.appCtx.RedisCache.Client.Expire(ctx, key, time.Hour).Err() // refresh TTL
+	s.adjustLikeCount(ctx, "expire", key) // refresh TTL
 
 	// check if recipient also liked actor → mutual
 	var mutual bool
@@ -219,3 +311,21 @@ func (s *Service) PutDecision(ctx context.Context, req *pb.PutDecisionRequest) (
 
 	return &pb.PutDecisionResponse{MutualLikes: mutual}, nil
 }
+
+// adjustLikeCount applies one like counter operation (incr, decr or
+// expire) and records a failure instead of failing the request.
+func (s *Service) adjustLikeCount(ctx context.Context, op, key string) {
+	var err error
+	switch op {
+	case "incr":
+		_, err = s.appCtx.RedisCache.Incr(ctx, key)
+	case "decr":
+		_, err = s.appCtx.RedisCache.Decr(ctx, key)
+	case "expire":
+		err = s.appCtx.RedisCache.Expire(ctx, key, s.appCtx.RedisCache.LikeCountTTL())
+	}
+	if err != nil {
+		metrics.CounterUpdateFailed(op)
+		logger.FromContext(ctx).DebugContext(ctx, "like counter update failed", "op", op, "key", key, "err", err)
+	}
+}
@@ -11,10 +11,13 @@ import (
 	"github.com/alicebob/miniredis/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
 	"github.com/oggyb/muzz-exercise/internal/app"
+	"github.com/oggyb/muzz-exercise/internal/auth"
 	"github.com/oggyb/muzz-exercise/internal/cache"
 	"github.com/oggyb/muzz-exercise/internal/config"
 	"github.com/oggyb/muzz-exercise/internal/db"
@@ -82,6 +85,14 @@ func SeedMinimalTestData(t *testing.T, gdb *gorm.DB) {
 // Each test gets its own isolated DB + Redis.
 func setupService(t *testing.T) *explore.Service {
 	t.Helper()
+	svc, _, _ := setupServiceWithRedis(t)
+	return svc
+}
+
+// setupServiceWithRedis is setupService that also returns the cache and
+// the fake Redis, for tests that need to break the connection.
+func setupServiceWithRedis(t *testing.T) (*explore.Service, *cache.RedisCache, *miniredis.Miniredis) {
+	t.Helper()
 
 	// In-memory SQLite
 	dbName := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
@@ -96,7 +107,7 @@ func setupService(t *testing.T) *explore.Service {
 	t.Cleanup(func() { sqlDB.Close() })
 
 	// Auto-migrate schema
-	require.NoError(t, dbase.AutoMigrate(&db.User{}, &db.Decision{}))
+	require.NoError(t, dbase.AutoMigrate(&db.User{}, &db.Decision{}, &db.UserStats{}))
 
 	// Seed data
 	SeedMinimalTestData(t, dbase)
@@ -113,7 +124,7 @@ func setupService(t *testing.T) *explore.Service {
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil)) // discard logs in tests
 
 	appCtx := app.New(dbase, redisCache, logger)
-	return explore.NewExploreService(appCtx)
+	return explore.NewExploreService(appCtx), redisCache, mr
 }
 
 //
@@ -162,6 +173,55 @@ func TestListNewLikedYou(t *testing.T) {
 	require.Len(t, resp.Likers, 0)
 }
 
+// TestPaginationTokenBoundToList checks that a ListLikedYou token cannot be
+// replayed on ListNewLikedYou or for another recipient.
+func TestPaginationTokenBoundToList(t *testing.T) {
+	ctx := context.Background()
+	svc := setupService(t)
+
+	// user1 has 1 liker; add more likers of user2 so the list spans pages
+	for _, actor := range []string{"3", "4", "5", "6", "7", "8"} {
+		_, err := svc.PutDecision(ctx, &pb.PutDecisionRequest{ActorUserId: actor, RecipientUserId: "2", LikedRecipient: true})
+		require.NoError(t, err)
+	}
+	first, err := svc.ListLikedYou(ctx, &pb.ListLikedYouRequest{RecipientUserId: "2"})
+	require.NoError(t, err)
+	require.NotNil(t, first.NextPaginationToken)
+
+	_, err = svc.ListLikedYou(ctx, &pb.ListLikedYouRequest{RecipientUserId: "2", PaginationToken: first.NextPaginationToken})
+	require.NoError(t, err)
+
+	for name, req := range map[string]func() error{
+		"other list": func() error {
+			_, err := svc.ListNewLikedYou(ctx, &pb.ListLikedYouRequest{RecipientUserId: "2", PaginationToken: first.NextPaginationToken})
+			return err
+		},
+		"other recipient": func() error {
+			_, err := svc.ListLikedYou(ctx, &pb.ListLikedYouRequest{RecipientUserId: "1", PaginationToken: first.NextPaginationToken})
+			return err
+		},
+	} {
+		assert.Equal(t, codes.InvalidArgument, status.Code(req()), name)
+	}
+}
+
+// TestCallerMustMatch checks that an authenticated caller can only act as
+// the actor of a decision and only read their own likes.
+func TestCallerMustMatch(t *testing.T) {
+	ctx := auth.WithUserID(context.Background(), 1)
+	svc := setupService(t)
+
+	_, err := svc.PutDecision(ctx, &pb.PutDecisionRequest{ActorUserId: "2", RecipientUserId: "3", LikedRecipient: true})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = svc.ListLikedYou(ctx, &pb.ListLikedYouRequest{RecipientUserId: "2"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = svc.CountLikedYou(ctx, &pb.CountLikedYouRequest{RecipientUserId: "2"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = svc.CountLikedYou(ctx, &pb.CountLikedYouRequest{RecipientUserId: "1"})
+	assert.NoError(t, err)
+}
+
 // TestCountLikedYouCache verifies like counts with cache.
 // Only user2 counts for user1. User3 is excluded due to a pass.
 func TestCountLikedYouCache(t *testing.T) {
@@ -178,3 +238,18 @@ func TestCountLikedYouCache(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, uint64(1), resp2.Count)
 }
+
+// TestCountLikedYouRedisDown verifies that counts are served from the DB
+// when Redis is unreachable, and that the circuit breaker trips.
+func TestCountLikedYouRedisDown(t *testing.T) {
+	ctx := context.Background()
+	svc, redisCache, mr := setupServiceWithRedis(t)
+	mr.Close()
+
+	for i := 0; i < 10; i++ {
+		resp, err := svc.CountLikedYou(ctx, &pb.CountLikedYouRequest{RecipientUserId: "1"})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), resp.Count)
+	}
+	assert.Equal(t, cache.StateOpen, redisCache.BreakerState())
+}
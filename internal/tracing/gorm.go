@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// GormPlugin opens a client span for every statement run through gorm,
+// with the SQL (placeholders, not values) and the database name given here.
+// gorm.ErrRecordNotFound is not treated as a failure.
+//
+// Behavior:
+//   - The span becomes the statement's context, so the driver call and
+//     statements gorm runs from it (e.g. association saves) nest under it.
+//   - SQL passed to Raw or Exec is not recorded: it is written by the
+//     caller and may have values formatted into it.
+//
+// Example:
+//
+//	db.Use(tracing.NewGormPlugin("shard1"))
+type GormPlugin struct {
+	db string
+}
+
+// NewGormPlugin returns a plugin labelling its spans with name.
+func NewGormPlugin(name string) *GormPlugin {
+	return &GormPlugin{db: name}
+}
+
+// Name implements gorm.Plugin.
+func (p *GormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize implements gorm.Plugin by wrapping each callback chain.
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", p.start("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", end),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", p.start("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", end),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", p.start("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", end),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", p.start("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", end),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", p.start("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", end),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", p.start("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", end),
+	)
+}
+
+func (p *GormPlugin) start(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil || !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			// not part of a traced request (migrations, backfill, seeding)
+			return
+		}
+		spanCtx, span := Tracer().Start(ctx, "gorm."+op,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system.name", db.Dialector.Name()),
+				attribute.String("db.namespace", p.db),
+				attribute.String("db.operation.name", op),
+			),
+		)
+		db.InstanceSet(spanKey, &statementSpan{
+			span:   span,
+			parent: ctx,
+			// Raw and Exec fill in the SQL before the callbacks run
+			callerSQL: db.Statement.SQL.Len() > 0,
+		})
+		db.Statement.Context = spanCtx
+	}
+}
+
+// statementSpan is what start hands to end for one statement.
+type statementSpan struct {
+	span      trace.Span
+	parent    context.Context
+	callerSQL bool
+}
+
+func end(db *gorm.DB) {
+	v, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	s := v.(*statementSpan)
+	span := s.span
+	defer span.End()
+	// later statements on this instance belong to the caller again
+	db.Statement.Context = s.parent
+
+	if db.Statement.Table != "" {
+		span.SetAttributes(attribute.String("db.collection.name", db.Statement.Table))
+	}
+	if !s.callerSQL {
+		span.SetAttributes(attribute.String("db.query.text", db.Statement.SQL.String()))
+	}
+	span.SetAttributes(attribute.Int64("db.response.returned_rows", db.RowsAffected))
+	if !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		RecordError(span, db.Error)
+	}
+}
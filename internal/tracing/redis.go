@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RedisHook is a go-redis hook opening a client span per command and per
+// pipeline. redis.Nil (a cache miss) is not treated as a failure.
+//
+// Example:
+//
+//	client.AddHook(tracing.RedisHook{})
+type RedisHook struct{}
+
+// DialHook implements redis.Hook.
+func (RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook implements redis.Hook.
+func (RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			return next(ctx, cmd)
+		}
+		ctx, span := Tracer().Start(ctx, "redis."+cmd.Name(),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system.name", "redis"),
+				attribute.String("db.operation.name", cmd.Name()),
+			),
+		)
+		defer span.End()
+
+		err := next(ctx, cmd)
+		if !errors.Is(err, redis.Nil) {
+			RecordError(span, err)
+		}
+		return err
+	}
+}
+
+// ProcessPipelineHook implements redis.Hook.
+func (RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			return next(ctx, cmds)
+		}
+		ctx, span := Tracer().Start(ctx, "redis.pipeline",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system.name", "redis"),
+				attribute.Int("db.operation.batch.size", len(cmds)),
+			),
+		)
+		defer span.End()
+
+		err := next(ctx, cmds)
+		if !errors.Is(err, redis.Nil) {
+			RecordError(span, err)
+		}
+		return err
+	}
+}
+
+var _ redis.Hook = RedisHook{}
@@ -0,0 +1,123 @@
+// Package tracing sets up OpenTelemetry and instruments the layers of the
+// server: gRPC interceptors, a gorm plugin, a go-redis hook and helpers for
+// repository spans.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/oggyb/muzz-exercise/internal/config"
+)
+
+// Supported values for config.Tracing.Exporter.
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+const instrumentationName = "github.com/oggyb/muzz-exercise"
+
+// Tracer returns the tracer used by all instrumentation in this module.
+// Until Init installs a provider it is a no-op.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start opens a span named name as a child of the span in ctx.
+//
+// Example:
+//
+//	ctx, span := tracing.Start(ctx, "DecisionRepository.GetLikers", attribute.Int64("recipient_id", int64(id)))
+//	defer span.End()
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// RecordError marks span as failed with err; nil is ignored.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Init installs the global tracer provider and W3C trace context
+// propagation from cfg.Tracing and returns a func flushing and stopping it.
+//
+// Behavior:
+//   - "none" (default) keeps the no-op provider; propagation still works.
+//   - "otlp" exports over gRPC to cfg.Tracing.Endpoint (host:port or URL),
+//     or per the standard OTEL_EXPORTER_OTLP_* variables when empty.
+//   - "stdout" prints spans as JSON, for local debugging.
+//   - Sampling follows the caller's decision, and samples
+//     cfg.Tracing.SampleRatio of new traces.
+func Init(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(cfg.Tracing.Exporter) {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		var opts []otlptracegrpc.Option
+		if ep := cfg.Tracing.Endpoint; strings.Contains(ep, "://") {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(ep))
+		} else if ep != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(ep))
+		}
+		if cfg.Tracing.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q (want none, otlp or stdout)", cfg.Tracing.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tracing: create %s exporter: %w", cfg.Tracing.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.Tracing.ServiceName),
+		attribute.String("deployment.environment.name", cfg.App.ENV),
+	))
+	if err != nil && !errors.Is(err, resource.ErrSchemaURLConflict) {
+		return nil, fmt.Errorf("tracing: resource: %w", err)
+	}
+
+	provider := NewProvider(exporter, cfg.Tracing.SampleRatio, sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider returns a batching tracer provider exporting to exporter and
+// sampling ratio of new traces. Tests pass a tracetest.InMemoryExporter.
+func NewProvider(exporter sdktrace.SpanExporter, ratio float64, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	}, opts...)
+	return sdktrace.NewTracerProvider(opts...)
+}
@@ -0,0 +1,104 @@
+package tracing_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/oggyb/muzz-exercise/internal/tracing"
+)
+
+// TestSpansFollowTheRequest runs a call carrying a traceparent through the
+// interceptor, gorm statements and a Redis command, and checks they end up
+// in one trace under the caller's span.
+func TestSpansFollowTheRequest(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	gdb, err := gorm.Open(sqlite.Open("file:tracing?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gdb.Use(tracing.NewGormPlugin("main")))
+	type widget struct{ ID uint }
+	require.NoError(t, gdb.AutoMigrate(&widget{}))
+	// the driver call sees the statement's span
+	var driverSpan trace.SpanID
+	require.NoError(t, gdb.Callback().Query().Before("gorm:query").After("tracing:before_query").
+		Register("test:driver_span", func(db *gorm.DB) {
+			driverSpan = trace.SpanFromContext(db.Statement.Context).SpanContext().SpanID()
+		}))
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	rdb.AddHook(tracing.RedisHook{})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"traceparent", "00-"+traceID+"-00f067aa0ba902b7-01",
+	))
+	handler := func(ctx context.Context, _ any) (any, error) {
+		var got []widget
+		if err := gdb.WithContext(ctx).Find(&got).Error; err != nil {
+			return nil, err
+		}
+		// SQL written by the caller may carry values; it is not recorded
+		if err := gdb.WithContext(ctx).Exec(fmt.Sprintf("DELETE FROM widgets WHERE id = %d", 42)).Error; err != nil {
+			return nil, err
+		}
+		// a cache miss is not an error
+		if err := rdb.Get(ctx, "missing").Err(); err != redis.Nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	_, err = tracing.UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/explore.ExploreService/ListLikedYou"}, handler)
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	byName := map[string]tracetest.SpanStub{}
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+	require.Contains(t, byName, "explore.ExploreService/ListLikedYou")
+	require.Contains(t, byName, "gorm.query")
+	require.Contains(t, byName, "redis.get")
+	require.Contains(t, byName, "gorm.raw")
+
+	server := byName["explore.ExploreService/ListLikedYou"]
+	assert.Equal(t, traceID, server.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	for _, name := range []string{"gorm.query", "redis.get"} {
+		assert.Equal(t, server.SpanContext.SpanID(), byName[name].Parent.SpanID(), name)
+		assert.Equal(t, "Unset", byName[name].Status.Code.String(), name)
+	}
+	assert.Equal(t, byName["gorm.query"].SpanContext.SpanID(), driverSpan)
+
+	attrs := func(name string) map[attribute.Key]string {
+		m := map[attribute.Key]string{}
+		for _, kv := range byName[name].Attributes {
+			m[kv.Key] = kv.Value.Emit()
+		}
+		return m
+	}
+	assert.Contains(t, attrs("gorm.query")["db.query.text"], "SELECT * FROM `widgets`")
+	assert.NotContains(t, attrs("gorm.raw"), attribute.Key("db.query.text"))
+}
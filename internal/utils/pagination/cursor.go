@@ -1,42 +1,203 @@
 package pagination
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 )
 
-// Cursor is the opaque pagination state we encode/decode.
-// ActorID + UpdatedUnix (in millis) establish a stable cursor.
+// Version is the current token format. Tokens of any other version are rejected.
+const Version = 1
+
+// DefaultTTL is how long a token stays valid when no TTL is configured.
+const DefaultTTL = 24 * time.Hour
+
+// ErrInvalidToken is returned (wrapped, with the reason) for any token that
+// is malformed, tampered with, expired or used on the wrong list.
+var ErrInvalidToken = errors.New("invalid pagination token")
+
+// Kind identifies the list a token belongs to.
+type Kind string
+
+const (
+	KindLikedYou    Kind = "liked_you"
+	KindNewLikedYou Kind = "new_liked_you"
+)
+
+// Cursor is the pagination state carried inside a token.
+// ActorID + UpdatedUnix (in millis) establish a stable cursor; Kind and
+// RecipientID bind it to one list of one user.
 type Cursor struct {
+	Version     int    `json:"v"`
+	Kind        Kind   `json:"k"`
+	RecipientID uint64 `json:"r"`
 	ActorID     uint64 `json:"actor_id"`
 	UpdatedUnix int64  `json:"updated_unix,omitempty"`
+	ExpiresUnix int64  `json:"exp"`
+
+	// UpdatedNanos is the sub-millisecond part of the position, so rows
+	// stored with micro- or nanosecond precision compare exactly. Tokens
+	// issued before it was added read as whole milliseconds.
+	UpdatedNanos int64 `json:"un,omitempty"`
+
+	// Backward marks a previous-page token: the page holds the entries
+	// just before (newer than) this position instead of after it.
+	Backward bool `json:"b,omitempty"`
+}
+
+// SetUpdatedAt stores t as the cursor position, at full precision.
+func (c *Cursor) SetUpdatedAt(t time.Time) {
+	c.UpdatedUnix = t.UnixMilli()
+	c.UpdatedNanos = int64(t.Nanosecond() % int(time.Millisecond))
+}
+
+// UpdatedAt returns the cursor position set by SetUpdatedAt.
+func (c Cursor) UpdatedAt() time.Time {
+	return time.UnixMilli(c.UpdatedUnix).Add(time.Duration(c.UpdatedNanos)).UTC()
+}
+
+// Key is one HMAC signing key. ID is embedded in tokens so the right key
+// can be picked after a rotation.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// ParseKeys parses "id:secret" entries (e.g. from PAGINATION_KEYS).
+// Secrets must be at least 32 bytes and IDs unique. Malformed entries are
+// reported by position (#1 is the first), so a secret is never echoed.
+func ParseKeys(specs []string) ([]Key, error) {
+	keys := make([]Key, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+	for i, spec := range specs {
+		id, secret, ok := strings.Cut(spec, ":")
+		if !ok || id == "" || strings.Contains(id, ".") {
+			return nil, fmt.Errorf("pagination key #%d: want id:secret", i+1)
+		}
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("pagination key %q: secret must be at least 32 bytes", id)
+		}
+		if seen[id] {
+			// only the first would ever verify tokens signed with this ID
+			return nil, fmt.Errorf("pagination key %q: duplicate ID", id)
+		}
+		seen[id] = true
+		keys = append(keys, Key{ID: id, Secret: []byte(secret)})
+	}
+	return keys, nil
+}
+
+// Codec signs and verifies pagination tokens.
+//
+// Format: base64(json cursor) "." key ID "." base64(HMAC-SHA256).
+//
+// Rotation: the first key signs, every key verifies. To rotate, put the new
+// key first and keep the old one until its tokens have expired (TTL).
+type Codec struct {
+	keys []Key
+	ttl  time.Duration
+	now  func() time.Time
 }
 
-// Encode converts a Cursor into a Base64 string.
-func Encode(c Cursor) (string, error) {
-	b, err := json.Marshal(c)
+// NewCodec creates a codec signing with keys[0]. ttl <= 0 uses DefaultTTL.
+func NewCodec(ttl time.Duration, keys ...Key) (*Codec, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("pagination: at least one signing key is required")
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Codec{keys: keys, ttl: ttl, now: time.Now}, nil
+}
+
+// NewRandomCodec creates a codec with a random key. Its tokens are only
+// valid within this process, so use it for tests and local development.
+func NewRandomCodec(ttl time.Duration) *Codec {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("pagination: %v", err))
+	}
+	c, _ := NewCodec(ttl, Key{ID: "ephemeral", Secret: secret})
+	return c
+}
+
+// Encode signs c with the active key, stamping the version and expiry.
+func (c *Codec) Encode(cur Cursor) (string, error) {
+	cur.Version = Version
+	cur.ExpiresUnix = c.now().Add(c.ttl).Unix()
+
+	b, err := json.Marshal(cur)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal cursor: %w", err)
 	}
-	return base64.URLEncoding.EncodeToString(b), nil
+	key := c.keys[0]
+	signed := base64.RawURLEncoding.EncodeToString(b) + "." + key.ID
+	return signed + "." + sign(key, signed), nil
 }
 
-// Decode parses a Base64 string into a Cursor.
+// Decode verifies token and checks that it belongs to the given list.
 // Empty token → empty cursor (first page).
-func Decode(token string) (Cursor, error) {
+//
+// Behavior:
+//   - Any failure wraps ErrInvalidToken with the reason.
+//   - Signature is checked before the payload is parsed.
+func (c *Codec) Decode(token string, kind Kind, recipientID uint64) (Cursor, error) {
 	if token == "" {
 		return Cursor{}, nil
 	}
 
-	b, err := base64.URLEncoding.DecodeString(token)
+	payload, rest, _ := strings.Cut(token, ".")
+	keyID, sig, ok := strings.Cut(rest, ".")
+	if !ok {
+		return Cursor{}, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+	key, ok := c.key(keyID)
+	if !ok {
+		return Cursor{}, fmt.Errorf("%w: unknown signing key", ErrInvalidToken)
+	}
+	if !hmac.Equal([]byte(sig), []byte(sign(key, payload+"."+keyID))) {
+		return Cursor{}, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(payload)
 	if err != nil {
-		return Cursor{}, fmt.Errorf("invalid pagination token")
+		return Cursor{}, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+	var cur Cursor
+	if err := json.Unmarshal(b, &cur); err != nil {
+		return Cursor{}, fmt.Errorf("%w: malformed", ErrInvalidToken)
 	}
 
-	var c Cursor
-	if err := json.Unmarshal(b, &c); err != nil {
-		return Cursor{}, fmt.Errorf("invalid pagination token")
+	switch {
+	case cur.Version != Version:
+		return Cursor{}, fmt.Errorf("%w: unsupported version %d", ErrInvalidToken, cur.Version)
+	case c.now().Unix() >= cur.ExpiresUnix:
+		return Cursor{}, fmt.Errorf("%w: expired", ErrInvalidToken)
+	case cur.Kind != kind:
+		return Cursor{}, fmt.Errorf("%w: issued for another list", ErrInvalidToken)
+	case cur.RecipientID != recipientID:
+		return Cursor{}, fmt.Errorf("%w: issued for another recipient", ErrInvalidToken)
 	}
-	return c, nil
+	return cur, nil
+}
+
+func (c *Codec) key(id string) (Key, bool) {
+	for _, k := range c.keys {
+		if k.ID == id {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+func sign(key Key, msg string) string {
+	mac := hmac.New(sha256.New, key.Secret)
+	mac.Write([]byte(msg))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
 }
@@ -0,0 +1,93 @@
+package pagination
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oldKey = Key{ID: "k1", Secret: []byte(strings.Repeat("a", 32))}
+	newKey = Key{ID: "k2", Secret: []byte(strings.Repeat("b", 32))}
+)
+
+func newTestCodec(t *testing.T, keys ...Key) (*Codec, *time.Time) {
+	t.Helper()
+	now := time.Unix(1_700_000_000, 0)
+	c, err := NewCodec(time.Hour, keys...)
+	require.NoError(t, err)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	c, _ := newTestCodec(t, oldKey)
+	token, err := c.Encode(Cursor{Kind: KindLikedYou, RecipientID: 42, ActorID: 7, UpdatedUnix: 123})
+	require.NoError(t, err)
+
+	cur, err := c.Decode(token, KindLikedYou, 42)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), cur.ActorID)
+	assert.Equal(t, int64(123), cur.UpdatedUnix)
+
+	cur, err = c.Decode("", KindLikedYou, 42)
+	require.NoError(t, err)
+	assert.Zero(t, cur)
+}
+
+func TestCodecRejects(t *testing.T) {
+	c, now := newTestCodec(t, oldKey)
+	token, err := c.Encode(Cursor{Kind: KindLikedYou, RecipientID: 42, ActorID: 7})
+	require.NoError(t, err)
+
+	// flip one character of the payload
+	tampered := []byte(token)
+	tampered[3] ^= 1
+
+	other, _ := newTestCodec(t, newKey)
+
+	for name, decode := range map[string]func() error{
+		"tampered":    func() error { _, err := c.Decode(string(tampered), KindLikedYou, 42); return err },
+		"garbage":     func() error { _, err := c.Decode("not-a-token", KindLikedYou, 42); return err },
+		"other list":  func() error { _, err := c.Decode(token, KindNewLikedYou, 42); return err },
+		"other user":  func() error { _, err := c.Decode(token, KindLikedYou, 43); return err },
+		"unknown key": func() error { _, err := other.Decode(token, KindLikedYou, 42); return err },
+		"expired":     func() error { *now = now.Add(2 * time.Hour); _, err := c.Decode(token, KindLikedYou, 42); return err },
+	} {
+		assert.ErrorIs(t, decode(), ErrInvalidToken, name)
+	}
+}
+
+func TestCodecKeyRotation(t *testing.T) {
+	before, _ := newTestCodec(t, oldKey)
+	token, err := before.Encode(Cursor{Kind: KindLikedYou, RecipientID: 1, ActorID: 2})
+	require.NoError(t, err)
+
+	// new key signs, old key still verifies
+	after, _ := newTestCodec(t, newKey, oldKey)
+	_, err = after.Decode(token, KindLikedYou, 1)
+	require.NoError(t, err)
+
+	fresh, err := after.Encode(Cursor{Kind: KindLikedYou, RecipientID: 1, ActorID: 2})
+	require.NoError(t, err)
+	assert.Contains(t, fresh, "."+newKey.ID+".")
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys([]string{"k2:" + strings.Repeat("x", 32), "k1:" + strings.Repeat("y", 40)})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "k2", keys[0].ID)
+
+	_, err = ParseKeys([]string{"short:secret"})
+	assert.Error(t, err)
+	_, err = ParseKeys([]string{"k1:" + strings.Repeat("x", 32), "nocolon-" + strings.Repeat("s", 32)})
+	assert.EqualError(t, err, "pagination key #2: want id:secret")
+	assert.NotContains(t, err.Error(), "sss", "the secret is not echoed")
+
+	_, err = ParseKeys([]string{"k1:" + strings.Repeat("x", 32), "k1:" + strings.Repeat("y", 32)})
+	assert.EqualError(t, err, `pagination key "k1": duplicate ID`)
+}